@@ -1,11 +1,84 @@
 package main
 
-import "github.com/doojin/file-explorer/server"
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/crypto"
+	"github.com/doojin/file-explorer/server"
+	"io"
+	"os"
+	"strings"
+)
 
+// Usage: file-explorer [genkey | hashpassword | print-config] [flags]
 func main() {
+	command, args := "", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	if command == "genkey" {
+		genkey()
+		return
+	}
+	if command == "hashpassword" {
+		hashpassword()
+		return
+	}
 	server := new(server.Server)
-	server.LoadXMLConfig("conf.xml")
-	server.Start()
+	if err := server.LoadConfig(args, os.Environ()); err == flag.ErrHelp {
+		return
+	} else if err != nil {
+		fail(err)
+	}
+	switch command {
+	case "":
+		if err := server.Start(); err != nil {
+			fail(err)
+		}
+	case "print-config":
+		if err := server.PrintConfig(os.Stdout); err != nil {
+			fail(err)
+		}
+	default:
+		fail(fmt.Errorf("unknown command %q, use genkey, hashpassword or print-config", command))
+	}
 }
 
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// genkey prints a random key, and a salt for deployments which derive the
+// key from a passphrase
+func genkey() {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		fail(err)
+	}
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("<key>%v</key>\n", key)
+	fmt.Printf("<passphraseSalt>%v</passphraseSalt>\n", salt)
+}
 
+// hashpassword prints the line of the users file for the user name and the
+// password read from standard input
+func hashpassword() {
+	if len(os.Args) != 3 {
+		fail(fmt.Errorf("usage: file-explorer hashpassword <user> < password.txt"))
+	}
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		fail(err)
+	}
+	hash, err := auth.HashPassword(strings.TrimRight(password, "\r\n"))
+	if err != nil {
+		fail(err)
+	}
+	fmt.Printf("%v:%v\n", os.Args[2], hash)
+}
@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type contextKey struct{}
+
+// entry collects what the handlers of a request learn about the event
+type entry struct {
+	mutex sync.Mutex
+	user  string
+	paths []string
+}
+
+// WithEntry returns the request with an empty audit entry, which handlers
+// fill with AddPath and SetUser
+func WithEntry(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, &entry{}))
+}
+
+// AddPath records a resolved path the request acts on. Requests without
+// an audit entry are ignored
+func AddPath(r *http.Request, path string) {
+	if entry, ok := r.Context().Value(contextKey{}).(*entry); ok {
+		entry.mutex.Lock()
+		entry.paths = append(entry.paths, path)
+		entry.mutex.Unlock()
+	}
+}
+
+// SetUser records the user of requests which don't come from a logged in
+// user, like logins and share links
+func SetUser(r *http.Request, user string) {
+	if entry, ok := r.Context().Value(contextKey{}).(*entry); ok {
+		entry.mutex.Lock()
+		entry.user = user
+		entry.mutex.Unlock()
+	}
+}
+
+// Fill sets the user and paths recorded for the request on the event. The
+// user is only set when it was recorded
+func Fill(r *http.Request, event *Event) {
+	entry, ok := r.Context().Value(contextKey{}).(*entry)
+	if !ok {
+		return
+	}
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if entry.user != "" {
+		event.User = entry.user
+	}
+	if len(entry.paths) > 0 {
+		event.Path = entry.paths[0]
+		event.Others = entry.paths[1:]
+	}
+}
@@ -0,0 +1,230 @@
+// audit package records who accessed or modified which files as JSON
+// lines in a log file which is rotated by size
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSize is the size at which the log file is rotated
+const DefaultMaxSize = 10 * 1024 * 1024
+
+// DefaultBackups is the number of rotated log files which are kept
+const DefaultBackups = 5
+
+// Results of events
+const (
+	ResultOK       = "ok"
+	ResultDenied   = "denied"
+	ResultNotFound = "not found"
+	ResultFailed   = "failed"
+)
+
+// Event is a single access or modification
+type Event struct {
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	IP     string    `json:"ip"`
+	Action string    `json:"action"`
+	Path   string    `json:"path,omitempty"`
+	// Others are further paths of actions on several files, like packing
+	// or comparing
+	Others []string `json:"others,omitempty"`
+	Result string   `json:"result"`
+	Status int      `json:"status"`
+	Bytes  int64    `json:"bytes"`
+}
+
+// Result returns the result of an event with the HTTP status
+func Result(status int) string {
+	switch {
+	case status < 400:
+		return ResultOK
+	case status == 401 || status == 403:
+		return ResultDenied
+	case status == 404 || status == 410:
+		return ResultNotFound
+	default:
+		return ResultFailed
+	}
+}
+
+// Log appends events to the log file. When the file would grow beyond
+// MaxSize it is renamed to file.1, file.1 to file.2 and so on, keeping
+// Backups rotated files
+type Log struct {
+	File    string
+	MaxSize int64
+	Backups int
+
+	mutex sync.Mutex
+	out   *os.File
+	size  int64
+}
+
+// Open opens the log file for appending. Zero maxSize and backups use the
+// defaults
+func Open(file string, maxSize int64, backups int) (*Log, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if backups <= 0 {
+		backups = DefaultBackups
+	}
+	log := &Log{File: file, MaxSize: maxSize, Backups: backups}
+	if err := log.open(); err != nil {
+		return nil, err
+	}
+	return log, nil
+}
+
+func (log *Log) open() (err error) {
+	log.out, err = os.OpenFile(log.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return
+	}
+	info, err := log.out.Stat()
+	if err != nil {
+		log.out.Close()
+		return
+	}
+	log.size = info.Size()
+	return
+}
+
+// Record appends the event to the log
+func (log *Log) Record(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	if log.size > 0 && log.size+int64(len(line)) > log.MaxSize {
+		if err = log.rotate(); err != nil {
+			return err
+		}
+	}
+	written, err := log.out.Write(line)
+	log.size += int64(written)
+	return err
+}
+
+// rotate moves the log file to the first backup and opens a new file
+func (log *Log) rotate() error {
+	log.out.Close()
+	os.Remove(log.backup(log.Backups))
+	for number := log.Backups - 1; number > 0; number-- {
+		os.Rename(log.backup(number), log.backup(number+1))
+	}
+	if err := os.Rename(log.File, log.backup(1)); err != nil {
+		return err
+	}
+	return log.open()
+}
+
+func (log *Log) backup(number int) string {
+	return log.File + "." + strconv.Itoa(number)
+}
+
+// Close closes the log file
+func (log *Log) Close() error {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	return log.out.Close()
+}
+
+// Filter selects events. Empty fields match every event, Path matches
+// events with a path containing it
+type Filter struct {
+	User   string
+	Action string
+	Path   string
+	Result string
+}
+
+// Matches tells whether the event is selected by the filter
+func (filter Filter) Matches(event Event) bool {
+	if filter.User != "" && event.User != filter.User {
+		return false
+	}
+	if filter.Action != "" && event.Action != filter.Action {
+		return false
+	}
+	if filter.Result != "" && event.Result != filter.Result {
+		return false
+	}
+	if filter.Path == "" {
+		return true
+	}
+	for _, path := range append([]string{event.Path}, event.Others...) {
+		if strings.Contains(path, filter.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Recent returns at most limit events selected by the filter, newest
+// first. It reads the rotated files as well when the current file has
+// fewer events. The files are only opened under the lock, so reading them
+// doesn't hold up Record, and rotation while reading doesn't mix them up
+func (log *Log) Recent(filter Filter, limit int) (events []Event, err error) {
+	files, err := log.openAll()
+	defer func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if len(events) >= limit {
+			break
+		}
+		content, err := ioutil.ReadAll(file)
+		if err != nil {
+			return nil, err
+		}
+		lines := bytes.Split(content, []byte("\n"))
+		for index := len(lines) - 1; index >= 0 && len(events) < limit; index-- {
+			var event Event
+			// Lines broken by a crash or still being written are skipped
+			if json.Unmarshal(lines[index], &event) == nil && filter.Matches(event) {
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
+// openAll opens the current file and the rotated files, newest first.
+// Files which don't exist are left out
+func (log *Log) openAll() (files []*os.File, err error) {
+	log.mutex.Lock()
+	defer log.mutex.Unlock()
+	for number := 0; number <= log.Backups; number++ {
+		name := log.File
+		if number > 0 {
+			name = log.backup(number)
+		}
+		file, err := os.Open(name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return
+}
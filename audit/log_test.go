@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testLog(t *testing.T, maxSize int64) (*Log, func()) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := Open(filepath.Join(dir, "audit.log"), maxSize, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return log, func() {
+		log.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_Log_ShouldReturnRecentEventsNewestFirst(t *testing.T) {
+	log, cleanup := testLog(t, 0)
+	defer cleanup()
+	log.Record(Event{User: "alice", Action: "download", Path: "/srv/a.txt", Result: ResultOK})
+	log.Record(Event{User: "bob", Action: "list", Path: "/srv", Result: ResultOK})
+	log.Record(Event{User: "alice", Action: "upload", Path: "/srv/in", Others: []string{"/srv/in/b.txt"}, Result: ResultDenied})
+
+	all, err := log.Recent(Filter{}, 10)
+	limited, _ := log.Recent(Filter{}, 2)
+	alice, _ := log.Recent(Filter{User: "alice"}, 10)
+	byPath, _ := log.Recent(Filter{Path: "b.txt"}, 10)
+	denied, _ := log.Recent(Filter{Result: ResultDenied, Action: "upload"}, 10)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, len(all))
+	assert.Equal(t, "upload", all[0].Action)
+	assert.Equal(t, "download", all[2].Action)
+	assert.Equal(t, 2, len(limited))
+	assert.Equal(t, 2, len(alice))
+	assert.Equal(t, 1, len(byPath))
+	assert.Equal(t, 1, len(denied))
+}
+
+func Test_Log_ShouldRotateBySize(t *testing.T) {
+	log, cleanup := testLog(t, 200)
+	defer cleanup()
+	for i := 0; i < 20; i++ {
+		log.Record(Event{User: "alice", Action: "list", Path: "/srv/files", Result: ResultOK})
+	}
+
+	info, _ := os.Stat(log.File)
+	_, firstErr := os.Stat(log.File + ".1")
+	_, secondErr := os.Stat(log.File + ".2")
+	_, thirdErr := os.Stat(log.File + ".3")
+	events, _ := log.Recent(Filter{}, 100)
+
+	assert.True(t, info.Size() <= 200)
+	assert.Equal(t, nil, firstErr)
+	assert.Equal(t, nil, secondErr)
+	assert.True(t, os.IsNotExist(thirdErr))
+	assert.True(t, len(events) > 2)
+	assert.True(t, len(events) < 20)
+}
+
+func Test_Log_ShouldReadWhileRotating(t *testing.T) {
+	log, cleanup := testLog(t, 200)
+	defer cleanup()
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 200; i++ {
+			log.Record(Event{User: "alice", Action: "list", Path: "/srv/files", Result: ResultOK})
+		}
+		close(done)
+	}()
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		_, err := log.Recent(Filter{}, 100)
+		assert.Equal(t, nil, err)
+	}
+	os.Remove(log.File + ".1")
+	events, err := log.Recent(Filter{}, 100)
+
+	assert.Equal(t, nil, err)
+	assert.True(t, len(events) > 0)
+}
+
+func Test_Log_ShouldAppendToExistingFile(t *testing.T) {
+	log, cleanup := testLog(t, 0)
+	defer cleanup()
+	log.Record(Event{Action: "list"})
+	log.Close()
+
+	reopened, _ := Open(log.File, 0, 0)
+	reopened.Record(Event{Action: "download"})
+	events, _ := reopened.Recent(Filter{}, 10)
+	reopened.Close()
+
+	assert.Equal(t, 2, len(events))
+}
+
+func Test_Result_ShouldClassifyStatus(t *testing.T) {
+	assert.Equal(t, ResultOK, Result(200))
+	assert.Equal(t, ResultOK, Result(303))
+	assert.Equal(t, ResultDenied, Result(403))
+	assert.Equal(t, ResultNotFound, Result(404))
+	assert.Equal(t, ResultFailed, Result(500))
+}
+
+func Test_Fill_ShouldSetRecordedUserAndPaths(t *testing.T) {
+	r := WithEntry(httptest.NewRequest("GET", "/", nil))
+	AddPath(r, "/srv/a.txt")
+	AddPath(r, "/srv/b.txt")
+	SetUser(r, "share 1")
+	event := Event{User: "alice"}
+	untouched := Event{User: "alice"}
+
+	Fill(r, &event)
+	Fill(httptest.NewRequest("GET", "/", nil), &untouched)
+
+	assert.Equal(t, "share 1", event.User)
+	assert.Equal(t, "/srv/a.txt", event.Path)
+	assert.Equal(t, []string{"/srv/b.txt"}, event.Others)
+	assert.Equal(t, Event{User: "alice"}, untouched)
+}
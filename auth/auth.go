@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CookieName is the name of the login session cookie
+const CookieName = "login"
+
+// LoginPath is the path of the login page
+const LoginPath = "/login/"
+
+// publicPrefixes are paths served without login: the login page, static
+// resources and share links, which have their own protection
+var publicPrefixes = []string{LoginPath, "/css/", "/img/", "/s/"}
+
+// basicCacheDuration is how long checked HTTP Basic credentials are
+// accepted without checking the bcrypt hash again
+const basicCacheDuration = time.Minute
+
+type contextKey struct{}
+
+// Identity is the logged in user with the groups the user belongs to
+type Identity struct {
+	Name   string
+	Groups []string
+}
+
+// Authenticator checks credentials of users and keeps their sessions.
+// Sessions and lockouts outlive the users, so the users file can be
+// loaded again without logging everybody out
+type Authenticator struct {
+	Users    *Users
+	Sessions *Sessions
+	Lockout  *Lockout
+
+	mutex    sync.Mutex
+	verified map[[sha256.Size]byte]time.Time
+}
+
+// Login checks the credentials and starts a session. A user is locked out
+// on the client after repeated failures from there, even when the password
+// is correct then. Other clients can still log in as the user
+func (authenticator *Authenticator) Login(user string, password string, client string) (id string, err error) {
+	if err = authenticator.check(user, password, client); err != nil {
+		return
+	}
+	return authenticator.Sessions.Create(user)
+}
+
+func (authenticator *Authenticator) check(user string, password string, client string) error {
+	key := client + "\x00" + user
+	if authenticator.Lockout.Locked(key) {
+		return ERR_LOCKED_OUT
+	}
+	if !authenticator.Users.Check(user, password) {
+		authenticator.Lockout.Fail(key)
+		return ERR_INVALID_CREDENTIALS
+	}
+	authenticator.Lockout.Succeed(key)
+	return nil
+}
+
+// checkBasic checks HTTP Basic credentials, which clients send with every
+// request. Correct credentials are remembered for basicCacheDuration, so
+// not every request pays for bcrypt
+func (authenticator *Authenticator) checkBasic(user string, password string, client string) error {
+	sum := sha256.Sum256([]byte(user + "\x00" + password))
+	now := time.Now()
+	authenticator.mutex.Lock()
+	until, ok := authenticator.verified[sum]
+	authenticator.mutex.Unlock()
+	if ok && now.Before(until) {
+		return nil
+	}
+	if err := authenticator.check(user, password, client); err != nil {
+		return err
+	}
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+	if authenticator.verified == nil {
+		authenticator.verified = map[[sha256.Size]byte]time.Time{}
+	}
+	for key, until := range authenticator.verified {
+		if !now.Before(until) {
+			delete(authenticator.verified, key)
+		}
+	}
+	authenticator.verified[sum] = now.Add(basicCacheDuration)
+	return nil
+}
+
+// Middleware lets through requests with a valid session cookie or HTTP
+// Basic credentials. Other requests are redirected to the login page,
+// or get 401 when they sent credentials or don't come from a browser
+func (authenticator *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range publicPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if cookie, err := r.Cookie(CookieName); err == nil {
+			if user, ok := authenticator.Sessions.User(cookie.Value); ok {
+				next.ServeHTTP(w, WithIdentity(r, authenticator.identity(user)))
+				return
+			}
+		}
+		if user, password, ok := r.BasicAuth(); ok {
+			err := authenticator.checkBasic(user, password, ClientAddress(r))
+			if err == nil {
+				next.ServeHTTP(w, WithIdentity(r, authenticator.identity(user)))
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="File Explorer"`)
+			http.Error(w, err.Error(), 401)
+			return
+		}
+		if r.Method != "GET" || !strings.Contains(r.Header.Get("Accept"), "text/html") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="File Explorer"`)
+			http.Error(w, "Login required", 401)
+			return
+		}
+		http.Redirect(w, r, LoginPath+"?next="+url.QueryEscape(r.URL.RequestURI()), 302)
+	})
+}
+
+// identity returns the identity of the user. Groups are read from the
+// current users file, so changes apply to existing sessions
+func (authenticator *Authenticator) identity(user string) Identity {
+	return Identity{user, authenticator.Users.Groups(user)}
+}
+
+// WithIdentity returns the request of the logged in user. Other identity
+// sources, like a reverse proxy which authenticates users, can use it to
+// identify users instead of Middleware
+func WithIdentity(r *http.Request, identity Identity) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, identity))
+}
+
+// IdentityOf returns the identity of the logged in user. It is empty when
+// authentication is disabled
+func IdentityOf(r *http.Request) Identity {
+	identity, _ := r.Context().Value(contextKey{}).(Identity)
+	return identity
+}
+
+// User returns the name of the logged in user of the request. It is empty
+// when authentication is disabled
+func User(r *http.Request) string {
+	return IdentityOf(r).Name
+}
+
+// ClientAddress returns the address of the client which sent the request,
+// without the port
+func ClientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testAuthenticator() *Authenticator {
+	return &Authenticator{
+		Users:    testUsers("alice"),
+		Sessions: NewSessions(time.Minute, time.Hour),
+		Lockout:  NewLockout(),
+	}
+}
+
+func serve(authenticator *Authenticator, r *http.Request) (w *httptest.ResponseRecorder, user string) {
+	w = httptest.NewRecorder()
+	authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user = User(r)
+	})).ServeHTTP(w, r)
+	return
+}
+
+func Test_Login_ShouldLockOutAfterRepeatedFailures(t *testing.T) {
+	authenticator := testAuthenticator()
+
+	id, err := authenticator.Login("alice", "alice password", "192.0.2.1")
+	_, wrongErr := authenticator.Login("alice", "wrong", "192.0.2.1")
+	for i := 1; i < MaxFailures; i++ {
+		authenticator.Login("alice", "wrong", "192.0.2.1")
+	}
+	_, lockedErr := authenticator.Login("alice", "alice password", "192.0.2.1")
+
+	assert.Equal(t, nil, err)
+	assert.NotEqual(t, "", id)
+	assert.Equal(t, ERR_INVALID_CREDENTIALS, wrongErr)
+	assert.Equal(t, ERR_LOCKED_OUT, lockedErr)
+}
+
+func Test_Login_ShouldAcceptUserAfterLockout(t *testing.T) {
+	now := time.Now()
+	authenticator := testAuthenticator()
+	authenticator.Lockout.now = func() time.Time { return now }
+
+	for i := 0; i < MaxFailures; i++ {
+		authenticator.Login("alice", "wrong", "192.0.2.1")
+	}
+	_, lockedErr := authenticator.Login("alice", "alice password", "192.0.2.1")
+	now = now.Add(LockoutDuration)
+	_, err := authenticator.Login("alice", "alice password", "192.0.2.1")
+
+	assert.Equal(t, ERR_LOCKED_OUT, lockedErr)
+	assert.Equal(t, nil, err)
+}
+
+func Test_Login_ShouldLockOutOnlyFailingClient(t *testing.T) {
+	authenticator := testAuthenticator()
+
+	for i := 0; i < MaxFailures; i++ {
+		wrong := httptest.NewRequest("GET", "/download/token/", nil)
+		wrong.RemoteAddr = "192.0.2.1:50000"
+		wrong.SetBasicAuth("alice", "wrong")
+		serve(authenticator, wrong)
+	}
+	_, lockedErr := authenticator.Login("alice", "alice password", "192.0.2.1")
+	_, err := authenticator.Login("alice", "alice password", "198.51.100.1")
+
+	assert.Equal(t, ERR_LOCKED_OUT, lockedErr)
+	assert.Equal(t, nil, err)
+}
+
+func Test_Middleware_ShouldAcceptSessionCookie(t *testing.T) {
+	authenticator := testAuthenticator()
+	id, _ := authenticator.Login("alice", "alice password", "192.0.2.1")
+	r := httptest.NewRequest("GET", "/scan/token/", nil)
+	r.AddCookie(&http.Cookie{Name: CookieName, Value: id})
+
+	w, user := serve(authenticator, r)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "alice", user)
+}
+
+func Test_Middleware_ShouldAcceptBasicCredentials(t *testing.T) {
+	authenticator := testAuthenticator()
+	r := httptest.NewRequest("GET", "/download/token/", nil)
+	r.SetBasicAuth("alice", "alice password")
+	wrong := httptest.NewRequest("GET", "/download/token/", nil)
+	wrong.SetBasicAuth("alice", "wrong")
+
+	w, user := serve(authenticator, r)
+	wrongW, wrongUser := serve(authenticator, wrong)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "alice", user)
+	assert.Equal(t, 401, wrongW.Code)
+	assert.Equal(t, "", wrongUser)
+	assert.NotEqual(t, "", wrongW.Header().Get("WWW-Authenticate"))
+}
+
+func Test_Middleware_ShouldRedirectBrowsersToLogin(t *testing.T) {
+	authenticator := testAuthenticator()
+	browser := httptest.NewRequest("GET", "/scan/token/?sort=name", nil)
+	browser.Header.Set("Accept", "text/html,application/xhtml+xml")
+	script := httptest.NewRequest("GET", "/scan/token/", nil)
+	share := httptest.NewRequest("GET", "/s/token/", nil)
+
+	browserW, _ := serve(authenticator, browser)
+	scriptW, _ := serve(authenticator, script)
+	shareW, _ := serve(authenticator, share)
+
+	assert.Equal(t, 302, browserW.Code)
+	assert.Equal(t, "/login/?next=%2Fscan%2Ftoken%2F%3Fsort%3Dname", browserW.Header().Get("Location"))
+	assert.Equal(t, 401, scriptW.Code)
+	assert.Equal(t, 200, shareW.Code)
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// Defaults of session expiry and lockout
+const (
+	DefaultIdleTimeout = 30 * time.Minute
+	DefaultMaxAge      = 12 * time.Hour
+	MaxFailures        = 5
+	LockoutDuration    = 15 * time.Minute
+)
+
+// sessionIDSize is the number of random bytes of session IDs
+const sessionIDSize = 32
+
+type session struct {
+	user      string
+	createdAt time.Time
+	lastSeen  time.Time
+}
+
+// Sessions keeps login sessions in memory. A session ends when it wasn't
+// used for the idle timeout, or at the latest after the maximum age
+type Sessions struct {
+	mutex    sync.Mutex
+	sessions map[string]*session
+	idle     time.Duration
+	maxAge   time.Duration
+	now      func() time.Time
+}
+
+// NewSessions creates an empty session store
+func NewSessions(idle time.Duration, maxAge time.Duration) *Sessions {
+	return &Sessions{sessions: map[string]*session{}, idle: idle, maxAge: maxAge, now: time.Now}
+}
+
+// Create starts a session of the user and returns its ID
+func (sessions *Sessions) Create(user string) (id string, err error) {
+	random := make([]byte, sessionIDSize)
+	if _, err = rand.Read(random); err != nil {
+		return
+	}
+	id = base64.RawURLEncoding.EncodeToString(random)
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+	now := sessions.now()
+	sessions.removeExpired(now)
+	sessions.sessions[id] = &session{user, now, now}
+	return
+}
+
+// User returns the user of the session and extends the idle timeout. The
+// second value is false for unknown and expired sessions
+func (sessions *Sessions) User(id string) (string, bool) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+	found, ok := sessions.sessions[id]
+	if !ok {
+		return "", false
+	}
+	now := sessions.now()
+	if sessions.expired(found, now) {
+		delete(sessions.sessions, id)
+		return "", false
+	}
+	found.lastSeen = now
+	return found.user, true
+}
+
+// Delete ends the session
+func (sessions *Sessions) Delete(id string) {
+	sessions.mutex.Lock()
+	defer sessions.mutex.Unlock()
+	delete(sessions.sessions, id)
+}
+
+func (sessions *Sessions) expired(found *session, now time.Time) bool {
+	return now.Sub(found.lastSeen) > sessions.idle || now.Sub(found.createdAt) > sessions.maxAge
+}
+
+func (sessions *Sessions) removeExpired(now time.Time) {
+	for id, found := range sessions.sessions {
+		if sessions.expired(found, now) {
+			delete(sessions.sessions, id)
+		}
+	}
+}
+
+// Lockout blocks logins of a user for LockoutDuration after MaxFailures
+// failed logins in a row
+type Lockout struct {
+	mutex    sync.Mutex
+	failures map[string]int
+	locked   map[string]time.Time
+	now      func() time.Time
+}
+
+// NewLockout creates a lockout without failures
+func NewLockout() *Lockout {
+	return &Lockout{failures: map[string]int{}, locked: map[string]time.Time{}, now: time.Now}
+}
+
+// Locked tells whether logins of the user are blocked
+func (lockout *Lockout) Locked(user string) bool {
+	lockout.mutex.Lock()
+	defer lockout.mutex.Unlock()
+	until, ok := lockout.locked[user]
+	if ok && !lockout.now().Before(until) {
+		delete(lockout.locked, user)
+		return false
+	}
+	return ok
+}
+
+// Fail counts a failed login of the user
+func (lockout *Lockout) Fail(user string) {
+	lockout.mutex.Lock()
+	defer lockout.mutex.Unlock()
+	lockout.failures[user]++
+	if lockout.failures[user] >= MaxFailures {
+		delete(lockout.failures, user)
+		lockout.locked[user] = lockout.now().Add(LockoutDuration)
+	}
+}
+
+// Succeed resets the failed logins of the user
+func (lockout *Lockout) Succeed(user string) {
+	lockout.mutex.Lock()
+	defer lockout.mutex.Unlock()
+	delete(lockout.failures, user)
+}
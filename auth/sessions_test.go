@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Sessions_ShouldExpireIdleSessions(t *testing.T) {
+	now := time.Now()
+	sessions := NewSessions(time.Minute, time.Hour)
+	sessions.now = func() time.Time { return now }
+	id, _ := sessions.Create("alice")
+
+	now = now.Add(50 * time.Second)
+	user, active := sessions.User(id)
+	now = now.Add(50 * time.Second)
+	_, stillActive := sessions.User(id)
+	now = now.Add(2 * time.Minute)
+	_, idle := sessions.User(id)
+
+	assert.Equal(t, "alice", user)
+	assert.True(t, active)
+	assert.True(t, stillActive)
+	assert.False(t, idle)
+}
+
+func Test_Sessions_ShouldExpireAfterMaxAge(t *testing.T) {
+	now := time.Now()
+	sessions := NewSessions(time.Minute, 3*time.Minute)
+	sessions.now = func() time.Time { return now }
+	id, _ := sessions.Create("alice")
+
+	for i := 0; i < 3; i++ {
+		now = now.Add(50 * time.Second)
+		sessions.User(id)
+	}
+	now = now.Add(50 * time.Second)
+	_, active := sessions.User(id)
+
+	assert.False(t, active)
+}
+
+func Test_Sessions_ShouldDeleteSessions(t *testing.T) {
+	sessions := NewSessions(time.Minute, time.Hour)
+	id, _ := sessions.Create("alice")
+
+	sessions.Delete(id)
+	_, active := sessions.User(id)
+	_, unknown := sessions.User("unknown")
+
+	assert.False(t, active)
+	assert.False(t, unknown)
+}
+
+func Test_Lockout_ShouldLockAfterRepeatedFailures(t *testing.T) {
+	now := time.Now()
+	lockout := NewLockout()
+	lockout.now = func() time.Time { return now }
+
+	for i := 0; i < MaxFailures-1; i++ {
+		lockout.Fail("alice")
+	}
+	lockout.Succeed("alice")
+	for i := 0; i < MaxFailures-1; i++ {
+		lockout.Fail("alice")
+	}
+	lockedBefore := lockout.Locked("alice")
+	lockout.Fail("alice")
+	locked := lockout.Locked("alice")
+	now = now.Add(LockoutDuration)
+	lockedAfter := lockout.Locked("alice")
+
+	assert.False(t, lockedBefore)
+	assert.True(t, locked)
+	assert.False(t, lockedAfter)
+	assert.False(t, lockout.Locked("bob"))
+}
@@ -0,0 +1,85 @@
+// auth package identifies users by their passwords and keeps their login
+// sessions
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/bcrypt"
+	"os"
+	"strings"
+)
+
+var (
+	ERR_INVALID_CREDENTIALS = errors.New("User name or password is not correct")
+	ERR_LOCKED_OUT          = errors.New("Too many failed logins, try again later")
+)
+
+// dummyHash is compared for unknown users, so that checking them takes as
+// long as checking known users
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// Users are the users allowed to log in with their bcrypt password hashes
+// and the groups they belong to
+type Users struct {
+	hashes map[string][]byte
+	groups map[string][]string
+}
+
+// LoadUsers reads the users file. Every line has the form name:hash, the
+// same as in htpasswd files with bcrypt hashes, optionally followed by
+// comma separated groups: name:hash:staff,admins. Empty lines and lines
+// starting with # are ignored
+func LoadUsers(filename string) (users *Users, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	users = &Users{hashes: map[string][]byte{}, groups: map[string][]string{}}
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) < 2 || parts[0] == "" {
+			return nil, fmt.Errorf("%v:%v: expected name:hash", filename, number)
+		}
+		if len(parts) == 3 {
+			for _, group := range strings.Split(parts[2], ",") {
+				if group = strings.TrimSpace(group); group != "" {
+					users.groups[parts[0]] = append(users.groups[parts[0]], group)
+				}
+			}
+		}
+		if _, err = bcrypt.Cost([]byte(parts[1])); err != nil {
+			return nil, fmt.Errorf("%v:%v: %v is not a bcrypt hash", filename, number, parts[0])
+		}
+		users.hashes[parts[0]] = []byte(parts[1])
+	}
+	return users, scanner.Err()
+}
+
+// HashPassword returns the bcrypt hash of the password for the users file
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// Groups returns the groups of the user
+func (users *Users) Groups(name string) []string {
+	return users.groups[name]
+}
+
+// Check tells whether the user exists and the password is correct
+func (users *Users) Check(name string, password string) bool {
+	hash, ok := users.hashes[name]
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil
+}
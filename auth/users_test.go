@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func writeUsers(content string) string {
+	file, _ := ioutil.TempFile("", "users")
+	file.WriteString(content)
+	file.Close()
+	return file.Name()
+}
+
+func testUsers(names ...string) *Users {
+	users := &Users{hashes: map[string][]byte{}}
+	for _, name := range names {
+		users.hashes[name], _ = bcrypt.GenerateFromPassword([]byte(name+" password"), bcrypt.MinCost)
+	}
+	return users
+}
+
+func Test_LoadUsers_ShouldReadNamesAndHashes(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret password"), bcrypt.MinCost)
+	file := writeUsers("# users\n\nalice:" + string(hash) + "\nbob:" + string(hash) + ":staff, admins\n")
+	defer os.Remove(file)
+
+	users, err := LoadUsers(file)
+
+	assert.Equal(t, nil, err)
+	assert.True(t, users.Check("alice", "secret password"))
+	assert.False(t, users.Check("alice", "wrong password"))
+	assert.True(t, users.Check("bob", "secret password"))
+	assert.False(t, users.Check("carol", "secret password"))
+	assert.Equal(t, []string(nil), users.Groups("alice"))
+	assert.Equal(t, []string{"staff", "admins"}, users.Groups("bob"))
+}
+
+func Test_LoadUsers_ShouldRejectMalformedLines(t *testing.T) {
+	noHash := writeUsers("alice\n")
+	defer os.Remove(noHash)
+	plainPassword := writeUsers("alice:secret\n")
+	defer os.Remove(plainPassword)
+
+	_, noHashErr := LoadUsers(noHash)
+	_, plainPasswordErr := LoadUsers(plainPassword)
+	_, missingErr := LoadUsers("missing-users")
+
+	assert.NotEqual(t, nil, noHashErr)
+	assert.NotEqual(t, nil, plainPasswordErr)
+	assert.NotEqual(t, nil, missingErr)
+}
+
+func Test_HashPassword_ShouldReturnBcryptHash(t *testing.T) {
+	hash, err := HashPassword("secret password")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, bcrypt.CompareHashAndPassword([]byte(hash), []byte("secret password")))
+}
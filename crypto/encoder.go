@@ -2,61 +2,165 @@
 package crypto
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
 	"io"
 	"encoding/base64"
 	"errors"
 	"strings"
+	"time"
 )
 
-var ERR_CIPHERTEXT_TOO_SHORT = errors.New("Ciphertext is too short")
+var (
+	ERR_CIPHERTEXT_TOO_SHORT = errors.New("Ciphertext is too short")
+	ERR_MALFORMED_TOKEN      = errors.New("Token is not a valid ciphertext")
+	ERR_INVALID_TOKEN        = errors.New("Token was modified or issued with another key")
+	ERR_UNKNOWN_KEY          = errors.New("Token was issued with an unknown key")
+	ERR_RETIRED_KEY          = errors.New("Token was issued with a retired key")
+	ERR_LEGACY_TOKEN         = errors.New("Tokens in the legacy format are not accepted anymore")
+)
 
-// Contains methods for encrypting / decrypting string data
+// Contains methods for encrypting / decrypting string data. Ciphertexts
+// are authenticated (AES-GCM), so modified tokens are rejected instead of
+// being decrypted into a different plaintext. Every token starts with the
+// ID of the key it was encrypted with, see Keyring
 type Encoder struct {
-	Cipher cipher.Block
+	keyring        *Keyring
+	legacyDeadline time.Time
+	deterministic  bool
 }
 
-// NewEncoder returns a new instance of Encoder
+// DefaultKeyID is the ID of the key used by NewEncoder
+const DefaultKeyID = "0"
+
+// NewEncoder returns a new instance of Encoder using a single key
 func NewEncoder(key string) (encoder Encoder, err error) {
-	cipher, err := aes.NewCipher([]byte(key))
+	return NewKeyringEncoder([]Key{{ID: DefaultKeyID, Secret: key, Active: true}})
+}
+
+// NewKeyringEncoder returns a new instance of Encoder which encrypts with
+// the active key and decrypts with any of the keys
+func NewKeyringEncoder(keys []Key) (encoder Encoder, err error) {
+	keyring, err := NewKeyring(keys)
 	if err != nil {
 		return
 	}
-	encoder = Encoder{Cipher: cipher}
+	encoder = Encoder{keyring: keyring}
 	return
 }
 
+// AcceptLegacyTokensUntil limits the time when tokens in the legacy format
+// (standard base64 with "/" replaced by "$") are accepted. Zero time means
+// they are accepted without a limit
+func (encoder *Encoder) AcceptLegacyTokensUntil(deadline time.Time) {
+	encoder.legacyDeadline = deadline
+}
+
+// UseDeterministicTokens makes Encrypt derive the nonce from the plaintext
+// instead of reading it from the random source, so the same path always
+// gets the same token under the same key. Such tokens can be bookmarked
+// and cached, but they reveal that two tokens carry the same path
+func (encoder *Encoder) UseDeterministicTokens(enabled bool) {
+	encoder.deterministic = enabled
+}
+
+// RetiredKeys returns IDs of the keys which are past their grace period
+func (encoder *Encoder) RetiredKeys() []string {
+	return encoder.keyring.Retired()
+}
+
 // Encrypt accepts plaintext and returns ciphertext
 func (encoder *Encoder) Encrypt(str string) (result string, err error) {
-	cipherText := make([]byte, aes.BlockSize+len(str))
-	iv := cipherText[:aes.BlockSize]
-	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+	return encoder.EncryptBound(str, "")
+}
+
+// EncryptBound returns ciphertext which can only be decrypted with the
+// same binding, for example the session of the user the token is issued
+// to. The binding is authenticated but not stored in the token
+func (encoder *Encoder) EncryptBound(str string, binding string) (result string, err error) {
+	id, aead := encoder.keyring.active()
+	data := additionalData(id, binding)
+	nonce := make([]byte, aead.NonceSize())
+	if encoder.deterministic {
+		// The nonce is a MAC of the additional data and the plaintext, so
+		// a nonce is only ever reused for the same input, which yields
+		// the same token
+		mac := hmac.New(sha256.New, encoder.keyring.nonceKeys[id])
+		binary.Write(mac, binary.BigEndian, uint32(len(data)))
+		mac.Write(data)
+		mac.Write([]byte(str))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
 		return
 	}
-	cfb := cipher.NewCFBEncrypter(encoder.Cipher, iv)
-	cfb.XORKeyStream(cipherText[aes.BlockSize:], []byte(str))
-	result = base64.StdEncoding.EncodeToString(cipherText)
-	result = strings.Replace(result, "/", "$", -1)
+	cipherText := append([]byte{byte(len(id))}, id...)
+	cipherText = append(cipherText, nonce...)
+	cipherText = aead.Seal(cipherText, nonce, []byte(str), data)
+	result = base64.RawURLEncoding.EncodeToString(cipherText)
 	return
 }
 
 // Decrypt accepts encrypted ciphertext and returns decrypted plaintext
 func (encoder *Encoder) Decrypt(str string) (result string, err error) {
-	str = strings.Replace(str, "$", "/", -1)
-	text, err := base64.StdEncoding.DecodeString(str)
+	return encoder.DecryptBound(str, "")
+}
+
+// DecryptBound decrypts ciphertext returned by EncryptBound with the same
+// binding
+func (encoder *Encoder) DecryptBound(str string, binding string) (result string, err error) {
+	text, err := encoder.decode(str)
 	if err != nil {
 		return
 	}
-	if len(text) < aes.BlockSize {
+	if len(text) < 1 || len(text) < 1+int(text[0]) {
 		err = ERR_CIPHERTEXT_TOO_SHORT
 		return
 	}
-	iv := text[:aes.BlockSize]
-	text = text[aes.BlockSize:]
-	cfb := cipher.NewCFBDecrypter(encoder.Cipher, iv)
-	cfb.XORKeyStream(text, text)
-	result = string(text)
+	id := string(text[1 : 1+int(text[0])])
+	text = text[1+len(id):]
+	aead, err := encoder.keyring.get(id)
+	if err != nil {
+		return
+	}
+	nonceSize := aead.NonceSize()
+	if len(text) < nonceSize+aead.Overhead() {
+		err = ERR_CIPHERTEXT_TOO_SHORT
+		return
+	}
+	plainText, err := aead.Open(nil, text[:nonceSize], text[nonceSize:], additionalData(id, binding))
+	if err != nil {
+		err = ERR_INVALID_TOKEN
+		return
+	}
+	result = string(plainText)
+	return
+}
+
+// additionalData authenticates the key ID and the binding of the token.
+// Unbound tokens authenticate only the key ID
+func additionalData(id string, binding string) []byte {
+	if binding == "" {
+		return []byte(id)
+	}
+	return []byte(id + "\x00" + binding)
+}
+
+// decode turns the token into bytes. Tokens are unpadded URL-safe base64,
+// legacy tokens are recognized by characters of the standard alphabet
+func (encoder *Encoder) decode(str string) (text []byte, err error) {
+	if strings.ContainsAny(str, "$+/=") {
+		if !encoder.legacyDeadline.IsZero() && !time.Now().Before(encoder.legacyDeadline) {
+			return nil, ERR_LEGACY_TOKEN
+		}
+		str = strings.Replace(str, "$", "/", -1)
+		text, err = base64.StdEncoding.DecodeString(str)
+	} else {
+		text, err = base64.RawURLEncoding.DecodeString(str)
+	}
+	if err != nil {
+		return nil, ERR_MALFORMED_TOKEN
+	}
 	return
-}
\ No newline at end of file
+}
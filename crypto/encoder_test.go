@@ -4,6 +4,9 @@ import (
 	"testing"
 	"github.com/stretchr/testify/assert"
 	"crypto/aes"
+	"encoding/base64"
+	"strings"
+	"time"
 )
 
 func Test_NewEncoder_ShouldNotReturnErrorIfKeySizeIsValid(t *testing.T) {
@@ -38,7 +41,7 @@ func Test_Encrypt_ShouldReturnEncryptedTextOfValidSize(t *testing.T) {
 	encryptedText, err := encoder.Encrypt(text)
 
 	assert.Equal(t, nil, err)
-	assert.Equal(t, 36, len(encryptedText))
+	assert.Equal(t, 54, len(encryptedText))
 }
 
 func Test_Decrypt_ShouldDecryptEncryptedTextCorrectly(t *testing.T) {
@@ -66,4 +69,139 @@ func Test_Decrypt_ShouldReturnErrorIfEncryptedTextHasWrongSize(t *testing.T) {
 
 	assert.Equal(t, "", decryptedText)
 	assert.Equal(t, ERR_CIPHERTEXT_TOO_SHORT, err)
-}
\ No newline at end of file
+}
+
+func Test_Decrypt_ShouldReturnErrorIfEncryptedTextWasModified(t *testing.T) {
+	encoder, _ := NewEncoder("123456789012345678901234")
+	encryptedText, _ := encoder.Encrypt("C:/share/public")
+	// Flipping a character of the ciphertext part
+	modified := []byte(encryptedText)
+	if modified[30] == 'A' {
+		modified[30] = 'B'
+	} else {
+		modified[30] = 'A'
+	}
+
+	decryptedText, err := encoder.Decrypt(string(modified))
+
+	assert.Equal(t, "", decryptedText)
+	assert.Equal(t, ERR_INVALID_TOKEN, err)
+}
+
+func Test_Decrypt_ShouldReturnErrorIfKeyIsDifferent(t *testing.T) {
+	encoder, _ := NewEncoder("123456789012345678901234")
+	otherEncoder, _ := NewEncoder("abcdefghijklmnopqrstuvwx")
+	encryptedText, _ := encoder.Encrypt("randomText")
+
+	_, err := otherEncoder.Decrypt(encryptedText)
+
+	assert.Equal(t, ERR_INVALID_TOKEN, err)
+}
+
+func Test_Decrypt_ShouldReturnErrorIfTextIsNotBase64(t *testing.T) {
+	encoder, _ := NewEncoder("123456789012345678901234")
+
+	_, err := encoder.Decrypt("not base64!")
+
+	assert.Equal(t, ERR_MALFORMED_TOKEN, err)
+}
+
+// legacyToken converts token into the legacy format. Plaintexts used with
+// it have lengths which make the legacy token padded, otherwise both
+// formats could be the same string
+func legacyToken(token string) string {
+	text, _ := base64.RawURLEncoding.DecodeString(token)
+	return strings.Replace(base64.StdEncoding.EncodeToString(text), "/", "$", -1)
+}
+
+func Test_Encrypt_ShouldReturnURLSafeTokens(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+
+	for i := 0; i < 100; i++ {
+		token, _ := encoder.Encrypt(strings.Repeat("?", i))
+
+		assert.False(t, strings.ContainsAny(token, "+/=$"), token)
+	}
+}
+
+func Test_Decrypt_ShouldAcceptLegacyTokens(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+	token, _ := encoder.Encrypt("C:/share/legacy/")
+
+	decryptedText, err := encoder.Decrypt(legacyToken(token) + "$")
+
+	assert.Equal(t, ERR_MALFORMED_TOKEN, err)
+
+	decryptedText, err = encoder.Decrypt(legacyToken(token))
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "C:/share/legacy/", decryptedText)
+}
+
+func Test_Decrypt_ShouldRejectLegacyTokensAfterDeadline(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+	token, _ := encoder.Encrypt("C:/share/legacy/")
+	encoder.AcceptLegacyTokensUntil(time.Now().Add(-time.Second))
+
+	_, legacyErr := encoder.Decrypt(legacyToken(token))
+	_, err := encoder.Decrypt(token)
+
+	assert.Equal(t, ERR_LEGACY_TOKEN, legacyErr)
+	assert.Equal(t, nil, err)
+}
+
+func Test_Encrypt_ShouldReturnSameTokenForSamePathInDeterministicMode(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+	encoder.UseDeterministicTokens(true)
+	otherEncoder, _ := NewEncoder("6543210987654321")
+	otherEncoder.UseDeterministicTokens(true)
+
+	token, _ := encoder.Encrypt("C:/share")
+	sameToken, _ := encoder.Encrypt("C:/share")
+	otherPathToken, _ := encoder.Encrypt("C:/share/")
+	otherKeyToken, _ := otherEncoder.Encrypt("C:/share")
+	decryptedText, err := encoder.Decrypt(token)
+
+	assert.Equal(t, token, sameToken)
+	assert.NotEqual(t, token, otherPathToken)
+	assert.NotEqual(t, token, otherKeyToken)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "C:/share", decryptedText)
+}
+
+func Test_Encrypt_ShouldReturnRandomTokensByDefault(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+
+	token, _ := encoder.Encrypt("C:/share")
+	otherToken, _ := encoder.Encrypt("C:/share")
+
+	assert.NotEqual(t, token, otherToken)
+}
+
+func Test_DecryptBound_ShouldRejectTokensOfOtherBindings(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+	token, _ := encoder.EncryptBound("C:/share", "session-a")
+
+	path, err := encoder.DecryptBound(token, "session-a")
+	_, otherErr := encoder.DecryptBound(token, "session-b")
+	_, unboundErr := encoder.Decrypt(token)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "C:/share", path)
+	assert.Equal(t, ERR_INVALID_TOKEN, otherErr)
+	assert.Equal(t, ERR_INVALID_TOKEN, unboundErr)
+}
+
+func Test_EncryptBound_ShouldUseDifferentTokensPerBindingInDeterministicMode(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+	encoder.UseDeterministicTokens(true)
+
+	token, _ := encoder.EncryptBound("C:/share", "session-a")
+	sameToken, _ := encoder.EncryptBound("C:/share", "session-a")
+	otherToken, _ := encoder.EncryptBound("C:/share", "session-b")
+	unboundToken, _ := encoder.Encrypt("C:/share")
+
+	assert.Equal(t, token, sameToken)
+	assert.NotEqual(t, token[:30], otherToken[:30])
+	assert.NotEqual(t, token[:30], unboundToken[:30])
+}
@@ -0,0 +1,136 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"time"
+)
+
+var (
+	ERR_NO_KEYS            = errors.New("At least one key is required")
+	ERR_INVALID_KEY_ID     = errors.New("Key ID must contain from 1 to 255 bytes")
+	ERR_DUPLICATE_KEY_ID   = errors.New("Key IDs must be unique")
+	ERR_NO_ACTIVE_KEY      = errors.New("Exactly one key must be active")
+	ERR_ACTIVE_KEY_RETIRED = errors.New("Active key can't be retired")
+)
+
+// Key is an encryption key with its identifier. The active key encrypts
+// new tokens, the other keys only decrypt tokens issued earlier. A key
+// with RetireAt set stops decrypting at that time, which gives bookmarked
+// links a grace period after the key was rotated
+type Key struct {
+	ID       string
+	Secret   string
+	Active   bool
+	RetireAt time.Time
+}
+
+// Keyring holds the keys accepted by Encoder
+type Keyring struct {
+	activeID  string
+	aeads     map[string]cipher.AEAD
+	macKeys   map[string][]byte
+	nonceKeys map[string][]byte
+	retireAt  map[string]time.Time
+	now       func() time.Time
+}
+
+// NewKeyring validates the keys and returns a new instance of Keyring.
+// A single key is active even if it isn't marked so
+func NewKeyring(keys []Key) (keyring *Keyring, err error) {
+	if len(keys) == 0 {
+		return nil, ERR_NO_KEYS
+	}
+	keyring = &Keyring{
+		aeads:     map[string]cipher.AEAD{},
+		macKeys:   map[string][]byte{},
+		nonceKeys: map[string][]byte{},
+		retireAt:  map[string]time.Time{},
+		now:       time.Now,
+	}
+	active := 0
+	for _, key := range keys {
+		if len(key.ID) == 0 || len(key.ID) > 255 {
+			return nil, ERR_INVALID_KEY_ID
+		}
+		if _, ok := keyring.aeads[key.ID]; ok {
+			return nil, ERR_DUPLICATE_KEY_ID
+		}
+		block, err := aes.NewCipher([]byte(key.Secret))
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		keyring.aeads[key.ID] = aead
+		keyring.macKeys[key.ID] = deriveKey([]byte(key.Secret), "signing")
+		keyring.nonceKeys[key.ID] = deriveKey([]byte(key.Secret), "nonce")
+		keyring.retireAt[key.ID] = key.RetireAt
+		if key.Active || len(keys) == 1 {
+			if !key.RetireAt.IsZero() {
+				return nil, ERR_ACTIVE_KEY_RETIRED
+			}
+			keyring.activeID = key.ID
+			active++
+		}
+	}
+	if active != 1 {
+		return nil, ERR_NO_ACTIVE_KEY
+	}
+	return
+}
+
+// Retired returns IDs of the keys which don't decrypt tokens anymore and
+// can be removed from the configuration
+func (keyring *Keyring) Retired() (ids []string) {
+	for id := range keyring.aeads {
+		if keyring.isRetired(id) {
+			ids = append(ids, id)
+		}
+	}
+	return
+}
+
+func (keyring *Keyring) active() (string, cipher.AEAD) {
+	return keyring.activeID, keyring.aeads[keyring.activeID]
+}
+
+// deriveKey derives a separate key for the purpose from the secret, so the
+// same secret is never used by two algorithms
+func deriveKey(secret []byte, purpose string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("file-explorer " + purpose))
+	return mac.Sum(nil)
+}
+
+func (keyring *Keyring) get(id string) (cipher.AEAD, error) {
+	aead, ok := keyring.aeads[id]
+	if !ok {
+		return nil, ERR_UNKNOWN_KEY
+	}
+	if keyring.isRetired(id) {
+		return nil, ERR_RETIRED_KEY
+	}
+	return aead, nil
+}
+
+func (keyring *Keyring) isRetired(id string) bool {
+	retireAt := keyring.retireAt[id]
+	return !retireAt.IsZero() && !keyring.now().Before(retireAt)
+}
+
+func (keyring *Keyring) macKey(id string) ([]byte, error) {
+	key, ok := keyring.macKeys[id]
+	if !ok {
+		return nil, ERR_UNKNOWN_KEY
+	}
+	if keyring.isRetired(id) {
+		return nil, ERR_RETIRED_KEY
+	}
+	return key, nil
+}
@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_NewKeyring_ShouldValidateKeys(t *testing.T) {
+	secret := "1234567890123456"
+
+	_, noKeysErr := NewKeyring(nil)
+	_, idErr := NewKeyring([]Key{{ID: "", Secret: secret}})
+	_, duplicateErr := NewKeyring([]Key{{ID: "a", Secret: secret, Active: true}, {ID: "a", Secret: secret}})
+	_, noActiveErr := NewKeyring([]Key{{ID: "a", Secret: secret}, {ID: "b", Secret: secret}})
+	_, twoActiveErr := NewKeyring([]Key{{ID: "a", Secret: secret, Active: true}, {ID: "b", Secret: secret, Active: true}})
+	_, retiredErr := NewKeyring([]Key{{ID: "a", Secret: secret, Active: true, RetireAt: time.Now()}})
+	_, singleErr := NewKeyring([]Key{{ID: "a", Secret: secret}})
+
+	assert.Equal(t, ERR_NO_KEYS, noKeysErr)
+	assert.Equal(t, ERR_INVALID_KEY_ID, idErr)
+	assert.Equal(t, ERR_DUPLICATE_KEY_ID, duplicateErr)
+	assert.Equal(t, ERR_NO_ACTIVE_KEY, noActiveErr)
+	assert.Equal(t, ERR_NO_ACTIVE_KEY, twoActiveErr)
+	assert.Equal(t, ERR_ACTIVE_KEY_RETIRED, retiredErr)
+	assert.Equal(t, nil, singleErr)
+}
+
+func Test_Decrypt_ShouldAcceptTokensOfInactiveKeys(t *testing.T) {
+	oldEncoder, _ := NewKeyringEncoder([]Key{{ID: "2016", Secret: "1234567890123456"}})
+	encoder, _ := NewKeyringEncoder([]Key{
+		{ID: "2016", Secret: "1234567890123456"},
+		{ID: "2017", Secret: "abcdefghijklmnop", Active: true},
+	})
+	oldToken, _ := oldEncoder.Encrypt("C:/share")
+
+	decrypted, err := encoder.Decrypt(oldToken)
+	newToken, _ := encoder.Encrypt("C:/share")
+	_, oldEncoderErr := oldEncoder.Decrypt(newToken)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "C:/share", decrypted)
+	assert.Equal(t, ERR_UNKNOWN_KEY, oldEncoderErr)
+}
+
+func Test_Decrypt_ShouldRejectTokensOfRetiredKeys(t *testing.T) {
+	retireAt := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldEncoder, _ := NewKeyringEncoder([]Key{{ID: "2016", Secret: "1234567890123456"}})
+	encoder, _ := NewKeyringEncoder([]Key{
+		{ID: "2016", Secret: "1234567890123456", RetireAt: retireAt},
+		{ID: "2017", Secret: "abcdefghijklmnop", Active: true},
+	})
+	token, _ := oldEncoder.Encrypt("C:/share")
+
+	encoder.keyring.now = func() time.Time { return retireAt.Add(-time.Second) }
+	_, graceErr := encoder.Decrypt(token)
+	encoder.keyring.now = func() time.Time { return retireAt }
+	_, retiredErr := encoder.Decrypt(token)
+
+	assert.Equal(t, nil, graceErr)
+	assert.Equal(t, ERR_RETIRED_KEY, retiredErr)
+	assert.Equal(t, []string{"2016"}, encoder.RetiredKeys())
+}
+
+func Test_Decrypt_ShouldRejectTokensWithSwappedKeyID(t *testing.T) {
+	encoder, _ := NewKeyringEncoder([]Key{
+		{ID: "a", Secret: "1234567890123456", Active: true},
+		{ID: "b", Secret: "1234567890123456"},
+	})
+	token, _ := encoder.Encrypt("C:/share")
+	// The first four characters encode the ID length, the ID and the
+	// first byte of the nonce
+	raw := []byte(token)
+	swapped, _ := NewKeyringEncoder([]Key{{ID: "b", Secret: "1234567890123456"}})
+	swappedToken, _ := swapped.Encrypt("C:/share")
+	copy(raw[:4], swappedToken[:4])
+
+	_, err := encoder.Decrypt(string(raw))
+
+	assert.Equal(t, ERR_INVALID_TOKEN, err)
+}
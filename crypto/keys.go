@@ -0,0 +1,141 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"golang.org/x/crypto/scrypt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+var (
+	ERR_MALFORMED_KEY   = errors.New("Key is not valid base64")
+	ERR_MISSING_KEY     = errors.New("Key is empty")
+	ERR_WEAK_KEY        = errors.New("Key is too weak, generate a random one with the genkey command")
+	ERR_WEAK_PASSPHRASE = errors.New("Passphrase is too short")
+	ERR_MISSING_SALT    = errors.New("Passphrase requires a salt, generate one with the genkey command")
+)
+
+// MinPassphraseLength is the minimal length of passphrases
+const MinPassphraseLength = 12
+
+// Keys with this prefix are base64 encoded, other keys are used as they
+// are
+const keyPrefix = "base64:"
+
+// Parameters of scrypt recommended for interactive logins. The key is
+// derived once at startup, so the cost is paid only once
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// knownKeys are sample keys from documentation and configuration files
+var knownKeys = []string{
+	"1234567890123456",
+	"abcdefghijklmnop",
+	"passwordpassword",
+}
+
+// GenerateKey returns a new random 256 bit key in the base64 form
+// accepted by ParseKey
+func GenerateKey() (string, error) {
+	return randomBase64(32)
+}
+
+// GenerateSalt returns a new random salt for PassphraseKey
+func GenerateSalt() (salt string, err error) {
+	salt, err = randomBase64(16)
+	return strings.TrimPrefix(salt, keyPrefix), err
+}
+
+// ParseKey returns the key written in a configuration file, key file or
+// environment variable. Keys starting with "base64:" are decoded
+func ParseKey(value string) (key string, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", ERR_MISSING_KEY
+	}
+	if !strings.HasPrefix(value, keyPrefix) {
+		return value, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, keyPrefix))
+	if err != nil {
+		return "", ERR_MALFORMED_KEY
+	}
+	return string(decoded), nil
+}
+
+// ReadKeyFile returns the key stored in the file
+func ReadKeyFile(filename string) (key string, err error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return
+	}
+	return ParseKey(string(content))
+}
+
+// KeyFromEnv returns the key stored in the environment variable
+func KeyFromEnv(name string) (key string, err error) {
+	return ParseKey(os.Getenv(name))
+}
+
+// PassphraseKey stretches the passphrase into a 256 bit key with scrypt.
+// The salt is base64 encoded and must stay the same, otherwise the derived
+// key changes
+func PassphraseKey(passphrase string, salt string) (key string, err error) {
+	if len(passphrase) < MinPassphraseLength {
+		return "", ERR_WEAK_PASSPHRASE
+	}
+	if salt == "" {
+		return "", ERR_MISSING_SALT
+	}
+	decodedSalt, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(salt, keyPrefix))
+	if err != nil {
+		return "", ERR_MALFORMED_KEY
+	}
+	derived, err := scrypt.Key([]byte(passphrase), decodedSalt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return
+	}
+	return string(derived), nil
+}
+
+// CheckKey rejects keys which are obviously weak: known sample keys, keys
+// made of few different bytes and keys which are mostly runs like "1234"
+// or "aaaa"
+func CheckKey(key string) error {
+	for _, known := range knownKeys {
+		if key == known {
+			return ERR_WEAK_KEY
+		}
+	}
+	distinct := map[byte]bool{}
+	for i := 0; i < len(key); i++ {
+		distinct[key[i]] = true
+	}
+	if len(distinct) < len(key)/2 {
+		return ERR_WEAK_KEY
+	}
+	steps := 0
+	for i := 1; i < len(key); i++ {
+		if diff := int(key[i]) - int(key[i-1]); diff >= -1 && diff <= 1 {
+			steps++
+		}
+	}
+	if steps*2 >= len(key) {
+		return ERR_WEAK_KEY
+	}
+	return nil
+}
+
+func randomBase64(size int) (string, error) {
+	random := make([]byte, size)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.StdEncoding.EncodeToString(random), nil
+}
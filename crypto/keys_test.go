@@ -0,0 +1,86 @@
+package crypto
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_CheckKey_ShouldRejectWeakKeys(t *testing.T) {
+	assert.Equal(t, ERR_WEAK_KEY, CheckKey("1234567890123456"))
+	assert.Equal(t, ERR_WEAK_KEY, CheckKey("aaaaaaaaaaaaaaaa"))
+	assert.Equal(t, ERR_WEAK_KEY, CheckKey("abababababababab"))
+	assert.Equal(t, ERR_WEAK_KEY, CheckKey("qrstuvwxyz012345"))
+	assert.Equal(t, nil, CheckKey("k3J9xQ2mP7vR4tW8"))
+}
+
+func Test_GenerateKey_ShouldReturnStrongKeysAcceptedByEncoder(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		generated, err := GenerateKey()
+		key, parseErr := ParseKey(generated)
+		_, encoderErr := NewEncoder(key)
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, nil, parseErr)
+		assert.Equal(t, 32, len(key))
+		assert.Equal(t, nil, CheckKey(key))
+		assert.Equal(t, nil, encoderErr)
+	}
+}
+
+func Test_ParseKey_ShouldDecodeBase64Keys(t *testing.T) {
+	key, err := ParseKey(" base64:azNKOXhRMm1QN3ZSNHRXOA==\n")
+	rawKey, rawErr := ParseKey("k3J9xQ2mP7vR4tW8\n")
+	_, malformedErr := ParseKey("base64:???")
+	_, missingErr := ParseKey(" ")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "k3J9xQ2mP7vR4tW8", key)
+	assert.Equal(t, nil, rawErr)
+	assert.Equal(t, "k3J9xQ2mP7vR4tW8", rawKey)
+	assert.Equal(t, ERR_MALFORMED_KEY, malformedErr)
+	assert.Equal(t, ERR_MISSING_KEY, missingErr)
+}
+
+func Test_ReadKeyFile_ShouldReadKeyFromFile(t *testing.T) {
+	ioutil.WriteFile("key_test.txt", []byte("base64:azNKOXhRMm1QN3ZSNHRXOA==\n"), 0600)
+	defer os.Remove("key_test.txt")
+
+	key, err := ReadKeyFile("key_test.txt")
+	_, missingErr := ReadKeyFile("missing_key_test.txt")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "k3J9xQ2mP7vR4tW8", key)
+	assert.NotEqual(t, nil, missingErr)
+}
+
+func Test_KeyFromEnv_ShouldReadKeyFromEnvironment(t *testing.T) {
+	os.Setenv("FILE_EXPLORER_TEST_KEY", "k3J9xQ2mP7vR4tW8")
+	defer os.Unsetenv("FILE_EXPLORER_TEST_KEY")
+
+	key, err := KeyFromEnv("FILE_EXPLORER_TEST_KEY")
+	_, missingErr := KeyFromEnv("FILE_EXPLORER_MISSING_TEST_KEY")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "k3J9xQ2mP7vR4tW8", key)
+	assert.Equal(t, ERR_MISSING_KEY, missingErr)
+}
+
+func Test_PassphraseKey_ShouldDeriveSameKeyForSameSalt(t *testing.T) {
+	salt, _ := GenerateSalt()
+	otherSalt, _ := GenerateSalt()
+
+	key, err := PassphraseKey("correct horse battery staple", salt)
+	sameKey, _ := PassphraseKey("correct horse battery staple", salt)
+	otherKey, _ := PassphraseKey("correct horse battery staple", otherSalt)
+	_, shortErr := PassphraseKey("short", salt)
+	_, saltErr := PassphraseKey("correct horse battery staple", "")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 32, len(key))
+	assert.Equal(t, key, sameKey)
+	assert.NotEqual(t, key, otherKey)
+	assert.Equal(t, ERR_WEAK_PASSPHRASE, shortErr)
+	assert.Equal(t, ERR_MISSING_SALT, saltErr)
+}
@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var ERR_INVALID_SIGNATURE = errors.New("Signature of the token is not valid")
+
+// Sign returns a token carrying the readable payload together with its
+// HMAC-SHA256 signature made with the active key. Unlike encrypted tokens,
+// signed tokens don't hide the payload. The purpose, like "pathref", is
+// signed along with the payload, so that tokens of one purpose can't be
+// used for another
+func (encoder *Encoder) Sign(purpose string, payload []byte) (token string) {
+	id, _ := encoder.keyring.active()
+	key, _ := encoder.keyring.macKey(id)
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+	encodedID := base64.RawURLEncoding.EncodeToString([]byte(id))
+	return encodedPayload + "." + encodedID + "." + signature(key, purpose, encodedPayload, encodedID)
+}
+
+// Verify checks the signature of the token signed for the purpose and
+// returns its payload
+func (encoder *Encoder) Verify(purpose string, token string) (payload []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ERR_MALFORMED_TOKEN
+	}
+	id, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ERR_MALFORMED_TOKEN
+	}
+	key, err := encoder.keyring.macKey(string(id))
+	if err != nil {
+		return
+	}
+	expected := signature(key, purpose, parts[0], parts[1])
+	if !hmac.Equal([]byte(expected), []byte(parts[2])) {
+		return nil, ERR_INVALID_SIGNATURE
+	}
+	payload, err = base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ERR_MALFORMED_TOKEN
+	}
+	return
+}
+
+func signature(key []byte, purpose string, encodedPayload string, encodedID string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(purpose + "\x00" + encodedPayload + "." + encodedID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
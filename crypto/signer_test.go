@@ -0,0 +1,53 @@
+package crypto
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Verify_ShouldReturnPayloadOfSignedToken(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+	token := encoder.Sign("test", []byte(`{"path":"C:/share"}`))
+
+	payload, err := encoder.Verify("test", token)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `{"path":"C:/share"}`, string(payload))
+	assert.False(t, strings.ContainsAny(token, "+/=$"))
+}
+
+func Test_Verify_ShouldRejectModifiedPayloadAndOtherPurposes(t *testing.T) {
+	encoder, _ := NewEncoder("1234567890123456")
+	token := encoder.Sign("test", []byte(`{"path":"C:/share"}`))
+	otherToken := encoder.Sign("test", []byte(`{"path":"C:/"}`))
+	parts := strings.Split(token, ".")
+	otherParts := strings.Split(otherToken, ".")
+
+	_, err := encoder.Verify("test", otherParts[0]+"."+parts[1]+"."+parts[2])
+	_, malformedErr := encoder.Verify("test", "no dots")
+	_, purposeErr := encoder.Verify("other", token)
+
+	assert.Equal(t, ERR_INVALID_SIGNATURE, err)
+	assert.Equal(t, ERR_INVALID_SIGNATURE, purposeErr)
+	assert.Equal(t, ERR_MALFORMED_TOKEN, malformedErr)
+}
+
+func Test_Verify_ShouldUseKeyringKeys(t *testing.T) {
+	retireAt := time.Now().Add(time.Hour)
+	oldEncoder, _ := NewKeyringEncoder([]Key{{ID: "old", Secret: "1234567890123456"}})
+	encoder, _ := NewKeyringEncoder([]Key{
+		{ID: "old", Secret: "1234567890123456", RetireAt: retireAt},
+		{ID: "new", Secret: "abcdefghijklmnop", Active: true},
+	})
+	token := oldEncoder.Sign("test", []byte("payload"))
+
+	payload, err := encoder.Verify("test", token)
+	encoder.keyring.now = func() time.Time { return retireAt }
+	_, retiredErr := encoder.Verify("test", token)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "payload", string(payload))
+	assert.Equal(t, ERR_RETIRED_KEY, retiredErr)
+}
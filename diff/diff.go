@@ -0,0 +1,166 @@
+// diff package compares texts line by line
+package diff
+
+import "strings"
+
+// Kinds of diff lines
+const (
+	Equal  = "equal"
+	Insert = "insert"
+	Delete = "delete"
+)
+
+// maxTraceSize limits the memory used by the search to about 8 MB of
+// saved diagonals, enough for about 1000 changed lines. Texts which differ
+// more are shown as completely replaced
+const maxTraceSize = 1 << 20
+
+// Line is a single line of a diff. Line numbers start with 1, zero means
+// the line is absent on that side
+type Line struct {
+	Kind      string
+	Text      string
+	OldNumber int
+	NewNumber int
+}
+
+// SplitLines splits text into lines without line terminators
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	text = strings.Replace(text, "\r\n", "\n", -1)
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Lines returns the shortest edit script turning old lines into new lines
+// using the Myers algorithm
+func Lines(old []string, new []string) (lines []Line) {
+	// Common prefix and suffix don't take part in the search
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix &&
+		old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Equal, old[i], i + 1, i + 1})
+	}
+	middle := myers(old[prefix:len(old)-suffix], new[prefix:len(new)-suffix])
+	for _, line := range middle {
+		if line.OldNumber > 0 {
+			line.OldNumber += prefix
+		}
+		if line.NewNumber > 0 {
+			line.NewNumber += prefix
+		}
+		lines = append(lines, line)
+	}
+	for i := 0; i < suffix; i++ {
+		oldIndex := len(old) - suffix + i
+		newIndex := len(new) - suffix + i
+		lines = append(lines, Line{Equal, old[oldIndex], oldIndex + 1, newIndex + 1})
+	}
+	return
+}
+
+// Changed checks if the diff contains any changes
+func Changed(lines []Line) bool {
+	for _, line := range lines {
+		if line.Kind != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+func myers(old []string, new []string) []Line {
+	n, m := len(old), len(new)
+	if n == 0 || m == 0 {
+		return replaceAll(old, new)
+	}
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+	traceSize := 0
+	for d := 0; d <= max; d++ {
+		// Step d only reads the diagonals -d-1 to d+1 of the previous step
+		traceSize += 2*d + 3
+		if traceSize > maxTraceSize {
+			return replaceAll(old, new)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && old[x] == new[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(old, new, trace)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the saved search states from the end to restore the
+// path. The state of step d holds the diagonals -d-1 to d+1
+func backtrack(old []string, new []string, trace [][]int) []Line {
+	x, y := len(old), len(new)
+	var reversed []Line
+	for d := len(trace) - 1; d >= 0 && (x > 0 || y > 0); d-- {
+		v := trace[d]
+		offset := d + 1
+		k := x - y
+		var previousK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousX := v[offset+previousK]
+		previousY := previousX - previousK
+		for x > previousX && y > previousY {
+			x--
+			y--
+			reversed = append(reversed, Line{Equal, old[x], x + 1, y + 1})
+		}
+		if d == 0 {
+			break
+		}
+		if x == previousX {
+			y--
+			reversed = append(reversed, Line{Insert, new[y], 0, y + 1})
+		} else {
+			x--
+			reversed = append(reversed, Line{Delete, old[x], x + 1, 0})
+		}
+	}
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
+
+func replaceAll(old []string, new []string) (lines []Line) {
+	for i, text := range old {
+		lines = append(lines, Line{Delete, text, i + 1, 0})
+	}
+	for i, text := range new {
+		lines = append(lines, Line{Insert, text, 0, i + 1})
+	}
+	return
+}
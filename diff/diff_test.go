@@ -0,0 +1,80 @@
+package diff
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func Test_SplitLines_ShouldSplitTextIntoLines(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, SplitLines("a\r\nb\n"))
+	assert.Equal(t, []string{"a", "", "b"}, SplitLines("a\n\nb"))
+	assert.Equal(t, []string(nil), SplitLines(""))
+}
+
+func Test_Lines_ShouldReturnShortestEditScript(t *testing.T) {
+	old := []string{"a", "b", "c", "a", "b", "b", "a"}
+	new := []string{"c", "b", "a", "b", "a", "c"}
+
+	lines := Lines(old, new)
+
+	changes := 0
+	var restoredOld, restoredNew []string
+	for _, line := range lines {
+		if line.Kind != Equal {
+			changes++
+		}
+		if line.Kind != Insert {
+			restoredOld = append(restoredOld, line.Text)
+		}
+		if line.Kind != Delete {
+			restoredNew = append(restoredNew, line.Text)
+		}
+	}
+	assert.Equal(t, 5, changes)
+	assert.Equal(t, old, restoredOld)
+	assert.Equal(t, new, restoredNew)
+}
+
+func Test_Lines_ShouldFindFewChangesInLongTexts(t *testing.T) {
+	var old, new []string
+	for i := 0; i < 100000; i++ {
+		old = append(old, strconv.Itoa(i))
+		if i%10000 == 5000 {
+			new = append(new, "changed")
+		} else {
+			new = append(new, strconv.Itoa(i))
+		}
+	}
+
+	changes := 0
+	for _, line := range Lines(old, new) {
+		if line.Kind != Equal {
+			changes++
+		}
+	}
+
+	assert.Equal(t, 20, changes)
+}
+
+func Test_Lines_ShouldNumberLinesOnBothSides(t *testing.T) {
+	lines := Lines([]string{"a", "b", "c"}, []string{"a", "x", "c"})
+
+	assert.Equal(t, []Line{
+		Line{Equal, "a", 1, 1},
+		Line{Delete, "b", 2, 0},
+		Line{Insert, "x", 0, 2},
+		Line{Equal, "c", 3, 3},
+	}, lines)
+}
+
+func Test_Lines_ShouldHandleEmptySides(t *testing.T) {
+	assert.Equal(t, []Line{Line{Insert, "a", 0, 1}}, Lines(nil, []string{"a"}))
+	assert.Equal(t, []Line{Line{Delete, "a", 1, 0}}, Lines([]string{"a"}, nil))
+	assert.Equal(t, []Line(nil), Lines(nil, nil))
+}
+
+func Test_Changed_ShouldDetectChanges(t *testing.T) {
+	assert.False(t, Changed(Lines([]string{"a"}, []string{"a"})))
+	assert.True(t, Changed(Lines([]string{"a"}, []string{"b"})))
+}
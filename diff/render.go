@@ -0,0 +1,155 @@
+package diff
+
+// maxIntralineLength is the line length above which changed lines are
+// highlighted completely instead of by characters
+const maxIntralineLength = 1000
+
+// Segment is a part of a line. Changed segments differ from the paired
+// line on the other side
+type Segment struct {
+	Text    string
+	Changed bool
+}
+
+// Cell is a rendered diff line. Line numbers are zero on the side where
+// the line is absent, an empty kind means there is no line at all
+type Cell struct {
+	Kind      string
+	OldNumber int
+	NewNumber int
+	Segments  []Segment
+}
+
+// Row is a pair of cells shown next to each other
+type Row struct {
+	Old Cell
+	New Cell
+}
+
+// Unified returns cells of a unified diff. Deleted and inserted lines of
+// the same change are paired for intraline highlights
+func Unified(lines []Line) (cells []Cell) {
+	for _, block := range blocks(lines) {
+		if block.equal != nil {
+			cells = append(cells, equalCell(*block.equal))
+			continue
+		}
+		deleted, inserted := pairCells(block.deleted, block.inserted)
+		cells = append(cells, deleted...)
+		cells = append(cells, inserted...)
+	}
+	return
+}
+
+// SideBySide returns rows of a two-column diff
+func SideBySide(lines []Line) (rows []Row) {
+	for _, block := range blocks(lines) {
+		if block.equal != nil {
+			cell := equalCell(*block.equal)
+			rows = append(rows, Row{cell, cell})
+			continue
+		}
+		deleted, inserted := pairCells(block.deleted, block.inserted)
+		for i := 0; i < len(deleted) || i < len(inserted); i++ {
+			var row Row
+			if i < len(deleted) {
+				row.Old = deleted[i]
+			}
+			if i < len(inserted) {
+				row.New = inserted[i]
+			}
+			rows = append(rows, row)
+		}
+	}
+	return
+}
+
+// block is either a single equal line or a change made of deleted and
+// inserted lines following each other
+type block struct {
+	equal    *Line
+	deleted  []Line
+	inserted []Line
+}
+
+func blocks(lines []Line) (result []block) {
+	var change *block
+	for i := range lines {
+		line := lines[i]
+		if line.Kind == Equal {
+			if change != nil {
+				result = append(result, *change)
+				change = nil
+			}
+			result = append(result, block{equal: &lines[i]})
+			continue
+		}
+		if change == nil {
+			change = &block{}
+		}
+		if line.Kind == Delete {
+			change.deleted = append(change.deleted, line)
+		} else {
+			change.inserted = append(change.inserted, line)
+		}
+	}
+	if change != nil {
+		result = append(result, *change)
+	}
+	return
+}
+
+func equalCell(line Line) Cell {
+	return Cell{Equal, line.OldNumber, line.NewNumber, []Segment{{line.Text, false}}}
+}
+
+func pairCells(deletedLines []Line, insertedLines []Line) (deleted []Cell, inserted []Cell) {
+	for i, line := range deletedLines {
+		segments := []Segment{{line.Text, true}}
+		if i < len(insertedLines) {
+			segments, _ = Intraline(line.Text, insertedLines[i].Text)
+		}
+		deleted = append(deleted, Cell{Delete, line.OldNumber, 0, segments})
+	}
+	for i, line := range insertedLines {
+		segments := []Segment{{line.Text, true}}
+		if i < len(deletedLines) {
+			_, segments = Intraline(deletedLines[i].Text, line.Text)
+		}
+		inserted = append(inserted, Cell{Insert, 0, line.NewNumber, segments})
+	}
+	return
+}
+
+// Intraline splits a pair of changed lines into segments, marking the
+// characters which differ
+func Intraline(old string, new string) (oldSegments []Segment, newSegments []Segment) {
+	oldChars, newChars := characters(old), characters(new)
+	if len(oldChars) > maxIntralineLength || len(newChars) > maxIntralineLength {
+		return []Segment{{old, true}}, []Segment{{new, true}}
+	}
+	for _, line := range Lines(oldChars, newChars) {
+		if line.Kind != Insert {
+			oldSegments = appendSegment(oldSegments, line.Text, line.Kind == Delete)
+		}
+		if line.Kind != Delete {
+			newSegments = appendSegment(newSegments, line.Text, line.Kind == Insert)
+		}
+	}
+	return
+}
+
+func characters(text string) (chars []string) {
+	for _, char := range text {
+		chars = append(chars, string(char))
+	}
+	return
+}
+
+func appendSegment(segments []Segment, text string, changed bool) []Segment {
+	if last := len(segments) - 1; last >= 0 && segments[last].Changed == changed {
+		segments[last].Text += text
+		return segments
+	}
+	return append(segments, Segment{text, changed})
+}
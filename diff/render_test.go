@@ -0,0 +1,62 @@
+package diff
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Intraline_ShouldMarkChangedCharacters(t *testing.T) {
+	oldSegments, newSegments := Intraline("port = 8080", "port = 9090")
+
+	assert.Equal(t, []Segment{
+		{"port = ", false},
+		{"8", true},
+		{"0", false},
+		{"8", true},
+		{"0", false},
+	}, oldSegments)
+	assert.Equal(t, []Segment{
+		{"port = ", false},
+		{"9", true},
+		{"0", false},
+		{"9", true},
+		{"0", false},
+	}, newSegments)
+}
+
+func Test_Intraline_ShouldMarkLongLinesCompletely(t *testing.T) {
+	long := strings.Repeat("a", maxIntralineLength+1)
+
+	oldSegments, newSegments := Intraline(long, "b")
+
+	assert.Equal(t, []Segment{{long, true}}, oldSegments)
+	assert.Equal(t, []Segment{{"b", true}}, newSegments)
+}
+
+func Test_Unified_ShouldPairChangedLines(t *testing.T) {
+	lines := Lines([]string{"a", "bx", "c"}, []string{"a", "by", "new", "c"})
+
+	cells := Unified(lines)
+
+	assert.Equal(t, []Cell{
+		{Equal, 1, 1, []Segment{{"a", false}}},
+		{Delete, 2, 0, []Segment{{"b", false}, {"x", true}}},
+		{Insert, 0, 2, []Segment{{"b", false}, {"y", true}}},
+		{Insert, 0, 3, []Segment{{"new", true}}},
+		{Equal, 3, 4, []Segment{{"c", false}}},
+	}, cells)
+}
+
+func Test_SideBySide_ShouldPutChangedLinesNextToEachOther(t *testing.T) {
+	lines := Lines([]string{"a", "b", "c"}, []string{"a", "x", "y", "c"})
+
+	rows := SideBySide(lines)
+
+	assert.Equal(t, 4, len(rows))
+	assert.Equal(t, Delete, rows[1].Old.Kind)
+	assert.Equal(t, Insert, rows[1].New.Kind)
+	assert.Equal(t, "", rows[2].Old.Kind)
+	assert.Equal(t, 3, rows[2].New.NewNumber)
+	assert.Equal(t, rows[3].Old, rows[3].New)
+}
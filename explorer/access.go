@@ -0,0 +1,111 @@
+package explorer
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ERR_ACCESS_DENIED      = errors.New("Access to this path is denied")
+	ERR_UNKNOWN_PERMISSION = errors.New("Unknown permission, use read, write, share or all")
+)
+
+// Permission is a set of operations allowed on a path
+type Permission int
+
+// Permissions checked by the explorer. Share permits creating share links
+// and is checked by the share controller
+const (
+	Read Permission = 1 << iota
+	Write
+	Share
+	AllPermissions = Read | Write | Share
+)
+
+var permissionNames = map[string]Permission{
+	"read":  Read,
+	"write": Write,
+	"share": Share,
+	"all":   AllPermissions,
+}
+
+// ParsePermissions parses a comma separated list of permissions like
+// "read,write"
+func ParsePermissions(list string) (permission Permission, err error) {
+	for _, name := range strings.Split(list, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		parsed, ok := permissionNames[name]
+		if !ok {
+			return 0, ERR_UNKNOWN_PERMISSION
+		}
+		permission |= parsed
+	}
+	return
+}
+
+// Rule allows and denies permissions on the path and everything below it.
+// The path is relative to the root of the explorer, like "/docs"
+type Rule struct {
+	Path  string
+	Allow Permission
+	Deny  Permission
+}
+
+// Rules decide the permissions of paths. For every permission the rule
+// with the longest path mentioning it wins, deny wins over allow on the
+// same path. Permissions no rule mentions are allowed
+type Rules []Rule
+
+// Allows checks if all the permissions are allowed on the path relative
+// to the root
+func (rules Rules) Allows(relative string, permission Permission) bool {
+	for bit := Read; bit <= Share; bit <<= 1 {
+		if permission&bit != 0 && !rules.allowsOne(relative, bit) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rules Rules) allowsOne(relative string, permission Permission) bool {
+	allowed, longest := true, -1
+	for _, rule := range rules {
+		path := strings.TrimSuffix(rule.Path, delimiter)
+		if !covers(path, relative) || len(path) < longest {
+			continue
+		}
+		mentioned := (rule.Allow|rule.Deny)&permission != 0
+		if !mentioned {
+			continue
+		}
+		denied := rule.Deny&permission != 0
+		if len(path) > longest {
+			allowed = !denied
+		} else if denied {
+			allowed = false
+		}
+		longest = len(path)
+	}
+	return allowed
+}
+
+// restrictedBelow checks if a rule below the path denies the permission,
+// so that operations on whole trees like packing can't bypass it
+func (rules Rules) restrictedBelow(relative string, permission Permission) bool {
+	relative = strings.TrimSuffix(relative, delimiter)
+	for _, rule := range rules {
+		path := strings.TrimSuffix(rule.Path, delimiter)
+		if rule.Deny&permission != 0 && len(path) > len(relative) && covers(relative, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// covers checks if the path is the prefix path or below it
+func covers(prefix string, path string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+delimiter)
+}
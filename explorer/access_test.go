@@ -0,0 +1,110 @@
+package explorer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_ParsePermissions_ShouldParseLists(t *testing.T) {
+	readWrite, err := ParsePermissions("read, Write")
+	all, _ := ParsePermissions("all")
+	none, _ := ParsePermissions("")
+	_, unknownErr := ParsePermissions("read,delete")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Read|Write, readWrite)
+	assert.Equal(t, AllPermissions, all)
+	assert.Equal(t, Permission(0), none)
+	assert.Equal(t, ERR_UNKNOWN_PERMISSION, unknownErr)
+}
+
+func Test_Rules_ShouldApplyLongestMatchingPath(t *testing.T) {
+	rules := Rules{
+		{Path: "", Allow: Read, Deny: Write | Share},
+		{Path: "/public", Allow: AllPermissions},
+		{Path: "/public/secret", Deny: Read},
+		{Path: "/team", Allow: Write},
+		{Path: "/team", Deny: Write},
+	}
+
+	assert.True(t, rules.Allows("/docs/a.txt", Read))
+	assert.False(t, rules.Allows("/docs/a.txt", Write))
+	assert.True(t, rules.Allows("/public/a.txt", Read|Write|Share))
+	assert.False(t, rules.Allows("/public/secret/a.txt", Read))
+	assert.True(t, rules.Allows("/public/secret/a.txt", Write))
+	assert.True(t, rules.Allows("/public-other", Read))
+	assert.False(t, rules.Allows("/public-other", Write))
+	assert.False(t, rules.Allows("/team/a.txt", Write))
+	assert.True(t, Rules{}.Allows("/a.txt", AllPermissions))
+}
+
+func Test_checkLevel_ShouldRejectSiblingsAndParentElements(t *testing.T) {
+	explorer := New("/srv/root")
+
+	assert.Equal(t, nil, explorer.checkLevel("/srv/root"))
+	assert.Equal(t, nil, explorer.checkLevel("/srv/root/docs/"))
+	assert.Equal(t, ERR_OUT_OF_ROOT, explorer.checkLevel("/srv/root2/docs"))
+	assert.Equal(t, ERR_OUT_OF_ROOT, explorer.checkLevel("/srv/root/../root2"))
+}
+
+func Test_Explorer_ShouldHideAndProtectDeniedEntries(t *testing.T) {
+	os.MkdirAll("rootDir/public", 0777)
+	os.MkdirAll("rootDir/secret", 0777)
+	ioutil.WriteFile("rootDir/public/a.txt", []byte("a"), 0777)
+	ioutil.WriteFile("rootDir/public/secret.txt", []byte("s"), 0777)
+	ioutil.WriteFile("rootDir/secret/b.txt", []byte("b"), 0777)
+	defer os.RemoveAll("rootDir")
+	explorer := Explorer{Root: "rootDir", Rules: Rules{
+		{Path: "/secret", Deny: Read},
+		{Path: "/public/secret.txt", Deny: Read},
+		{Path: "/public", Deny: Write},
+	}}
+
+	directories, _ := explorer.RootDirectories()
+	files, _ := explorer.Files("rootDir/public")
+	found, foundDirectories := explorer.FindEntities("rootDir", "secret", 0, 0)
+	_, _, openErr := explorer.Open("rootDir/secret/b.txt")
+	_, writeErr := explorer.CreateFile("rootDir/public", "new.txt", strings.NewReader("x"), 10)
+	packErr := explorer.Pack([]string{"rootDir"}, "rootDir/all.zip", nil)
+
+	assert.Equal(t, []Directory{{Name: "public", Path: "rootDir/public"}}, directories)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, "a.txt", files[0].Name)
+	assert.Equal(t, 0, len(found))
+	assert.Equal(t, 0, len(foundDirectories))
+	assert.Equal(t, ERR_ACCESS_DENIED, openErr)
+	assert.Equal(t, ERR_ACCESS_DENIED, writeErr)
+	assert.Equal(t, ERR_ACCESS_DENIED, packErr)
+}
+
+func Test_Explorer_ShouldApplyRulesOfArchivesToMembers(t *testing.T) {
+	explorer := Explorer{Root: "/srv/root", Rules: Rules{{Path: "/docs/secret.zip", Deny: Read}}}
+
+	assert.Equal(t, ERR_ACCESS_DENIED, explorer.Check("/srv/root/docs/secret.zip!/", Read))
+	assert.Equal(t, ERR_ACCESS_DENIED, explorer.Check("/srv/root/docs/secret.zip!/a.txt", Read))
+	assert.Equal(t, ERR_OUT_OF_ROOT, explorer.Check("/srv/root/../secret.zip!/a.txt", Read))
+	assert.True(t, explorer.Allows("/srv/root/docs/public.zip!/a.txt", Read))
+}
+
+func Test_Explorer_ShouldNotWriteBelowReadOnlyPaths(t *testing.T) {
+	os.MkdirAll("rootDir/archive/2017", 0777)
+	os.MkdirAll("rootDir/docs", 0777)
+	ioutil.WriteFile("rootDir/archive/a.txt", []byte("a"), 0777)
+	defer os.RemoveAll("rootDir")
+	explorer := Explorer{Root: "rootDir", ReadOnly: []string{"rootDir/archive/2017"},
+		Rules: Rules{{Path: "/archive", Allow: AllPermissions}}}
+
+	_, nestedErr := explorer.CreateFile("rootDir/archive/2017", "new.txt", strings.NewReader("x"), 10)
+	_, siblingErr := explorer.CreateFile("rootDir/archive", "new.txt", strings.NewReader("x"), 10)
+	treeErr := explorer.Extract("rootDir/docs.zip", "rootDir/archive", DefaultArchiveLimits, nil)
+	_, _, readErr := explorer.Open("rootDir/archive/a.txt")
+
+	assert.Equal(t, ERR_ACCESS_DENIED, nestedErr)
+	assert.Equal(t, nil, siblingErr)
+	assert.Equal(t, ERR_ACCESS_DENIED, treeErr)
+	assert.Equal(t, nil, readErr)
+	assert.True(t, explorer.Allows("rootDir/docs", Write))
+}
@@ -0,0 +1,318 @@
+package explorer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveDelimiter separates the archive file path from the path of
+// the member inside the archive, e.g. "root/delivery.zip!/docs/readme.txt"
+const archiveDelimiter = "!"
+
+var archiveExtensions = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// archiveEntry describes a single member of an archive
+type archiveEntry struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+// IsArchive checks if the provided file name has a supported archive extension
+func IsArchive(name string) bool {
+	name = strings.ToLower(name)
+	for _, extension := range archiveExtensions {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsArchive checks if the file is an archive which can be browsed
+func (file File) IsArchive() bool {
+	return IsArchive(file.Name)
+}
+
+// splitArchivePath splits path into the archive file path and the member
+// path inside the archive. A bare archive path is treated as the archive root
+func splitArchivePath(path string) (archive string, member string, ok bool) {
+	path = strings.TrimSuffix(path, delimiter)
+	for i := 0; i < len(path); i++ {
+		if path[i:i+1] != archiveDelimiter {
+			continue
+		}
+		rest := path[i+1:]
+		if (rest == "" || strings.HasPrefix(rest, delimiter)) && IsArchive(path[:i]) {
+			return path[:i], strings.Trim(rest, delimiter), true
+		}
+	}
+	if IsArchive(path) {
+		return path, "", true
+	}
+	return
+}
+
+// archiveLocation resolves path inside an archive. Directories which only
+// look like archives by name are not treated as archives
+func archiveLocation(path string) (archive string, member string, ok bool) {
+	archive, member, ok = splitArchivePath(path)
+	if !ok {
+		return
+	}
+	info, err := os.Stat(archive)
+	ok = err == nil && !info.IsDir()
+	return
+}
+
+func buildArchivePath(archive string, member string) string {
+	return archive + archiveDelimiter + delimiter + member
+}
+
+func (explorer *Explorer) archiveDirectories(archive string, member string) (directories []Directory, err error) {
+	entries, err := readArchive(archive)
+	if err != nil {
+		err = ERR_CANNOT_SCAN
+		return
+	}
+	for _, entry := range archiveChildren(entries, member) {
+		if entry.isDir {
+			directories = append(directories, Directory{
+				entry.name,
+				buildArchivePath(archive, joinMember(member, entry.name)),
+			})
+		}
+	}
+	return
+}
+
+func (explorer *Explorer) archiveFiles(archive string, member string) (files []File, err error) {
+	entries, err := readArchive(archive)
+	if err != nil {
+		err = ERR_CANNOT_SCAN
+		return
+	}
+	for _, entry := range archiveChildren(entries, member) {
+		if !entry.isDir {
+			files = append(files, File{
+				Name:    entry.name,
+				Size:    entry.size,
+				Path:    buildArchivePath(archive, joinMember(member, entry.name)),
+				ModTime: entry.modTime,
+			})
+		}
+	}
+	return
+}
+
+// archiveChildren returns direct children of the member directory. Archives
+// don't always contain entries for intermediate directories, so those are
+// derived from member paths
+func archiveChildren(entries []archiveEntry, member string) (children []archiveEntry) {
+	prefix := ""
+	if member != "" {
+		prefix = member + delimiter
+	}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.name, prefix) || entry.name == prefix {
+			continue
+		}
+		rest := strings.TrimPrefix(entry.name, prefix)
+		name := rest
+		isDir := entry.isDir
+		if i := strings.Index(rest, delimiter); i >= 0 {
+			name = rest[:i]
+			isDir = true
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		child := entry
+		child.name = name
+		child.isDir = isDir
+		if isDir {
+			child.size = 0
+		}
+		children = append(children, child)
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].name < children[j].name
+	})
+	return
+}
+
+func joinMember(member string, name string) string {
+	if member == "" {
+		return name
+	}
+	return member + delimiter + name
+}
+
+func readArchive(archive string) (entries []archiveEntry, err error) {
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		return readZip(archive)
+	}
+	return readTar(archive)
+}
+
+func readZip(archive string) (entries []archiveEntry, err error) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		entries = append(entries, archiveEntry{
+			name:    cleanMemberName(file.Name),
+			size:    int64(file.UncompressedSize64),
+			modTime: file.Modified,
+			isDir:   file.FileInfo().IsDir(),
+		})
+	}
+	return
+}
+
+func readTar(archive string) (entries []archiveEntry, err error) {
+	reader, closer, err := openTar(archive)
+	if err != nil {
+		return
+	}
+	defer closer.Close()
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg {
+			continue
+		}
+		entries = append(entries, archiveEntry{
+			name:    cleanMemberName(header.Name),
+			size:    header.Size,
+			modTime: header.ModTime,
+			isDir:   header.Typeflag == tar.TypeDir,
+		})
+	}
+}
+
+func openTar(archive string) (reader *tar.Reader, closer io.Closer, err error) {
+	file, err := os.Open(archive)
+	if err != nil {
+		return
+	}
+	var source io.Reader = file
+	name := strings.ToLower(archive)
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		source, err = gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return
+		}
+	}
+	return tar.NewReader(source), file, nil
+}
+
+func cleanMemberName(name string) string {
+	name = strings.Replace(name, "\\", delimiter, -1)
+	name = strings.TrimPrefix(name, "./")
+	return strings.Trim(name, delimiter)
+}
+
+// archiveMember wraps a member reader together with the archive it was
+// read from, so that closing the member releases the archive
+type archiveMember struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (member *archiveMember) Close() (err error) {
+	for _, closer := range member.closers {
+		if closeErr := closer.Close(); closeErr != nil {
+			err = closeErr
+		}
+	}
+	return
+}
+
+func openArchiveMember(archive string, member string) (reader io.ReadCloser, file File, err error) {
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		return openZipMember(archive, member)
+	}
+	return openTarMember(archive, member)
+}
+
+func openZipMember(archive string, member string) (reader io.ReadCloser, file File, err error) {
+	zipReader, err := zip.OpenReader(archive)
+	if err != nil {
+		err = ERR_CANNOT_READ
+		return
+	}
+	for _, zipFile := range zipReader.File {
+		if cleanMemberName(zipFile.Name) != member || zipFile.FileInfo().IsDir() {
+			continue
+		}
+		content, openErr := zipFile.Open()
+		if openErr != nil {
+			zipReader.Close()
+			err = ERR_CANNOT_READ
+			return
+		}
+		reader = &archiveMember{content, []io.Closer{content, zipReader}}
+		file = memberFile(archive, member, int64(zipFile.UncompressedSize64), zipFile.Modified)
+		return
+	}
+	zipReader.Close()
+	err = ERR_NOT_FOUND
+	return
+}
+
+func openTarMember(archive string, member string) (reader io.ReadCloser, file File, err error) {
+	tarReader, closer, err := openTar(archive)
+	if err != nil {
+		err = ERR_CANNOT_READ
+		return
+	}
+	for {
+		header, nextErr := tarReader.Next()
+		if nextErr == io.EOF {
+			err = ERR_NOT_FOUND
+			break
+		}
+		if nextErr != nil {
+			err = ERR_CANNOT_READ
+			break
+		}
+		if header.Typeflag == tar.TypeReg && cleanMemberName(header.Name) == member {
+			reader = &archiveMember{tarReader, []io.Closer{closer}}
+			file = memberFile(archive, member, header.Size, header.ModTime)
+			return
+		}
+	}
+	closer.Close()
+	return
+}
+
+func memberFile(archive string, member string, size int64, modTime time.Time) File {
+	name := member
+	if i := strings.LastIndex(member, delimiter); i >= 0 {
+		name = member[i+1:]
+	}
+	return File{
+		Name:    name,
+		Size:    size,
+		Path:    buildArchivePath(archive, member),
+		ModTime: modTime,
+	}
+}
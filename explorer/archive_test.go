@@ -0,0 +1,168 @@
+package explorer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+var archiveModTime = time.Date(2016, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func createZip(name string, members map[string]string) {
+	file, _ := os.Create(name)
+	defer file.Close()
+	writer := zip.NewWriter(file)
+	for memberName, content := range members {
+		header := &zip.FileHeader{Name: memberName, Method: zip.Deflate}
+		header.Modified = archiveModTime
+		member, _ := writer.CreateHeader(header)
+		member.Write([]byte(content))
+	}
+	writer.Close()
+}
+
+func createTarGz(name string, members map[string]string) {
+	file, _ := os.Create(name)
+	defer file.Close()
+	gzipWriter := gzip.NewWriter(file)
+	defer gzipWriter.Close()
+	writer := tar.NewWriter(gzipWriter)
+	defer writer.Close()
+	for memberName, content := range members {
+		writer.WriteHeader(&tar.Header{
+			Name:     memberName,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			ModTime:  archiveModTime,
+			Typeflag: tar.TypeReg,
+		})
+		writer.Write([]byte(content))
+	}
+}
+
+var archiveMembers = map[string]string{
+	"readme.txt":        "hello",
+	"docs/manual.txt":   "manual",
+	"docs/img/logo.png": "png",
+}
+
+func Test_Directories_ShouldListDirectoriesInsideArchives(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createZip("rootDir/delivery.zip", archiveMembers)
+	createTarGz("rootDir/delivery.tar.gz", archiveMembers)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	zipDirectories, zipErr := explorer.Directories("rootDir/delivery.zip")
+	tarDirectories, tarErr := explorer.Directories("rootDir/delivery.tar.gz!/docs")
+
+	assert.Equal(t, nil, zipErr)
+	assert.Equal(t, []Directory{
+		Directory{Name: "docs", Path: "rootDir/delivery.zip!/docs"},
+	}, zipDirectories)
+	assert.Equal(t, nil, tarErr)
+	assert.Equal(t, []Directory{
+		Directory{Name: "img", Path: "rootDir/delivery.tar.gz!/docs/img"},
+	}, tarDirectories)
+}
+
+func Test_Files_ShouldListFilesInsideArchivesWithSizesAndDates(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createZip("rootDir/delivery.zip", archiveMembers)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	files, err := explorer.Files("rootDir/delivery.zip!/docs")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(files))
+	assert.Equal(t, "manual.txt", files[0].Name)
+	assert.Equal(t, "rootDir/delivery.zip!/docs/manual.txt", files[0].Path)
+	assert.Equal(t, int64(6), files[0].Size)
+	assert.True(t, archiveModTime.Equal(files[0].ModTime))
+}
+
+func Test_Files_ShouldReturnErrorIfArchiveIsBroken(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	ioutil.WriteFile("rootDir/broken.zip", []byte("not a zip"), 0777)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	_, err := explorer.Files("rootDir/broken.zip")
+
+	assert.Equal(t, ERR_CANNOT_SCAN, err)
+}
+
+func Test_Directories_ShouldNotTreatDirectoryNamedLikeArchiveAsArchive(t *testing.T) {
+	os.MkdirAll("rootDir/folder.zip/inner", 0777)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	directories, err := explorer.Directories("rootDir/folder.zip")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []Directory{
+		Directory{Name: "inner", Path: "rootDir/folder.zip/inner"},
+	}, directories)
+}
+
+func Test_Open_ShouldReadArchiveMembers(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createZip("rootDir/delivery.zip", archiveMembers)
+	createTarGz("rootDir/delivery.tgz", archiveMembers)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	zipReader, zipFile, zipErr := explorer.Open("rootDir/delivery.zip!/docs/manual.txt")
+	zipContent, _ := ioutil.ReadAll(zipReader)
+	zipReader.Close()
+	tarReader, tarFile, tarErr := explorer.Open("rootDir/delivery.tgz!/readme.txt")
+	tarContent, _ := ioutil.ReadAll(tarReader)
+	tarReader.Close()
+
+	assert.Equal(t, nil, zipErr)
+	assert.Equal(t, "manual", string(zipContent))
+	assert.Equal(t, "manual.txt", zipFile.Name)
+	assert.Equal(t, nil, tarErr)
+	assert.Equal(t, "hello", string(tarContent))
+	assert.Equal(t, int64(5), tarFile.Size)
+}
+
+func Test_Open_ShouldReturnErrorIfMemberNotExists(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createZip("rootDir/delivery.zip", archiveMembers)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	_, _, err := explorer.Open("rootDir/delivery.zip!/unknown.txt")
+
+	assert.Equal(t, ERR_NOT_FOUND, err)
+}
+
+func Test_Open_ShouldReturnErrorIfFileIsOutsideTheRoot(t *testing.T) {
+	explorer := New("rootDir")
+
+	_, _, err := explorer.Open("otherDir/file.txt")
+
+	assert.Equal(t, ERR_OUT_OF_ROOT, err)
+}
+
+func Test_splitArchivePath_ShouldSplitArchiveAndMemberPaths(t *testing.T) {
+	archive, member, ok := splitArchivePath("C:/share/delivery.tar.gz!/docs/readme.txt")
+	assert.Equal(t, "C:/share/delivery.tar.gz", archive)
+	assert.Equal(t, "docs/readme.txt", member)
+	assert.True(t, ok)
+
+	archive, member, ok = splitArchivePath("C:/share/delivery.zip")
+	assert.Equal(t, "C:/share/delivery.zip", archive)
+	assert.Equal(t, "", member)
+	assert.True(t, ok)
+
+	_, _, ok = splitArchivePath("C:/share/wow!/readme.txt")
+	assert.False(t, ok)
+}
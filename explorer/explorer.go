@@ -6,6 +6,7 @@ import (
 	"os"
 	"errors"
 	"strings"
+	"io"
 )
 
 // DELIMITER is a directory separator
@@ -14,11 +15,20 @@ const delimiter = "/"
 var (
 	ERR_CANNOT_SCAN = errors.New("Cannot scan this directory")
 	ERR_OUT_OF_ROOT = errors.New("Directory you try to scan is outside the root directory")
+	ERR_CANNOT_READ = errors.New("Cannot read this file")
+	ERR_NOT_FOUND = errors.New("File not found")
 )
 
-// Explorer structure contains methods for directory scanning
+// Explorer structure contains methods for directory scanning. Rules
+// restrict what can be done below Root, entries which can't be read are
+// left out of listings and search results. Nothing is written below the
+// ReadOnly paths, whatever the rules allow. Scanned, if set, is called
+// for every directory read from disk
 type Explorer struct {
-	Root string
+	Root     string
+	Rules    Rules
+	ReadOnly []string
+	Scanned  func()
 }
 
 // New returns a new instance of Explorer
@@ -38,41 +48,178 @@ func (explorer *Explorer) RootFiles() (files []File, err error) {
 
 // Directories returns a slice of directories within the provided path
 func (explorer *Explorer) Directories(path string) (directories []Directory, err error) {
-	if err = explorer.checkLevel(path); err != nil {
+	if err = explorer.Check(path, Read); err != nil {
 		return
 	}
-	entities, err := ioutil.ReadDir(path)
-	if err != nil {
-		err = ERR_CANNOT_SCAN
-		return
+	if archive, member, ok := archiveLocation(path); ok {
+		directories, err = explorer.archiveDirectories(archive, member)
+	} else {
+		entities, readErr := ioutil.ReadDir(path)
+		if readErr != nil {
+			err = ERR_CANNOT_SCAN
+			return
+		}
+		if explorer.Scanned != nil {
+			explorer.Scanned()
+		}
+		directories = filterDirectories(entities, path)
 	}
-	directories = filterDirectories(entities, path)
-	return
+	readable := directories[:0]
+	for _, directory := range directories {
+		if explorer.Allows(directory.Path, Read) {
+			readable = append(readable, directory)
+		}
+	}
+	return readable, err
 }
 
 // Files returns a slice of files within the provided path
 func (explorer *Explorer) Files(path string) (files []File, err error) {
-	if err = explorer.checkLevel(path); err != nil {
+	if err = explorer.Check(path, Read); err != nil {
 		return
 	}
-	entities, err := ioutil.ReadDir(path)
+	if archive, member, ok := archiveLocation(path); ok {
+		files, err = explorer.archiveFiles(archive, member)
+	} else {
+		entities, readErr := ioutil.ReadDir(path)
+		if readErr != nil {
+			err = ERR_CANNOT_SCAN
+			return
+		}
+		files = filterFiles(entities, path)
+	}
+	readable := files[:0]
+	for _, file := range files {
+		if explorer.Allows(file.Path, Read) {
+			readable = append(readable, file)
+		}
+	}
+	return readable, err
+}
+
+// Open returns a reader for the file located at the provided path. Paths
+// inside archives are opened as archive members
+func (explorer *Explorer) Open(path string) (reader io.ReadCloser, file File, err error) {
+	if err = explorer.Check(path, Read); err != nil {
+		return
+	}
+	if archive, member, ok := archiveLocation(path); ok && member != "" {
+		return openArchiveMember(archive, member)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		err = ERR_NOT_FOUND
+		return
+	}
+	osFile, err := os.Open(path)
 	if err != nil {
-		err = ERR_CANNOT_SCAN
+		err = ERR_CANNOT_READ
 		return
 	}
-	files = filterFiles(entities, path)
+	reader = osFile
+	file = File{
+		Name:    info.Name(),
+		Size:    info.Size(),
+		Path:    path,
+		ModTime: info.ModTime(),
+	}
 	return
 }
 
+// IsDir checks if the path is a directory. Members of archives are not
+// supported
+func (explorer *Explorer) IsDir(path string) (isDir bool, err error) {
+	if err = explorer.Check(path, Read); err != nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, ERR_NOT_FOUND
+	}
+	return info.IsDir(), nil
+}
+
+// checkLevel makes sure the path is the root or below it. Paths with ".."
+// elements are rejected, as well as siblings sharing a prefix with the
+// root, like /srv/root2 for the root /srv/root
 func (explorer *Explorer) checkLevel(path string) (err error) {
-	root := strings.TrimSuffix(explorer.Root, delimiter)
-	path = strings.TrimSuffix(path, delimiter)
-	if !strings.HasPrefix(path, root) {
+	if _, ok := explorer.relative(path); !ok {
 		err = ERR_OUT_OF_ROOT
 	}
 	return
 }
 
+// Check returns ERR_OUT_OF_ROOT for paths outside the root and
+// ERR_ACCESS_DENIED when the rules don't allow the permissions
+func (explorer *Explorer) Check(path string, permission Permission) error {
+	relative, ok := explorer.relative(path)
+	if !ok {
+		return ERR_OUT_OF_ROOT
+	}
+	if !explorer.Rules.Allows(relative, permission) {
+		return ERR_ACCESS_DENIED
+	}
+	if permission&Write != 0 {
+		for _, readOnly := range explorer.ReadOnly {
+			if covers(strings.TrimSuffix(readOnly, delimiter), strings.TrimSuffix(path, delimiter)) {
+				return ERR_ACCESS_DENIED
+			}
+		}
+	}
+	return nil
+}
+
+// Allows checks if the path is below the root and the rules allow the
+// permissions
+func (explorer *Explorer) Allows(path string, permission Permission) bool {
+	return explorer.Check(path, permission) == nil
+}
+
+// CheckTree checks the permissions on the path and on everything below it,
+// so that operations on whole trees like sharing and packing can't bypass
+// rules below the path
+func (explorer *Explorer) CheckTree(path string, permission Permission) error {
+	if err := explorer.Check(path, permission); err != nil {
+		return err
+	}
+	if relative, _ := explorer.relative(path); explorer.Rules.restrictedBelow(relative, permission) {
+		return ERR_ACCESS_DENIED
+	}
+	if permission&Write != 0 {
+		for _, readOnly := range explorer.ReadOnly {
+			if covers(strings.TrimSuffix(path, delimiter), strings.TrimSuffix(readOnly, delimiter)) {
+				return ERR_ACCESS_DENIED
+			}
+		}
+	}
+	return nil
+}
+
+// relative returns the path relative to the root, like "/docs/a.txt". It
+// is empty for the root itself. Explorers without root accept all paths.
+// Paths inside archives are relative paths of the archive file, so rules
+// on the archive cover its members
+func (explorer *Explorer) relative(path string) (relative string, ok bool) {
+	root := strings.TrimSuffix(explorer.Root, delimiter)
+	if archive, _, isArchive := splitArchivePath(path); isArchive {
+		path = archive
+	}
+	path = strings.TrimSuffix(path, delimiter)
+	if explorer.Root == "" {
+		relative = delimiter + strings.TrimPrefix(path, delimiter)
+	} else if path == root || strings.HasPrefix(path, root+delimiter) {
+		relative = path[len(root):]
+	} else {
+		return "", false
+	}
+	for _, element := range strings.Split(relative, delimiter) {
+		if element == ".." {
+			return "", false
+		}
+	}
+	return relative, true
+}
+
 // FindEntities searches for files and folders with specified name
 func (explorer *Explorer) FindEntities(path string, name string, level int, currentLevel int) (resultFiles []File, resultDirectories []Directory) {
 	// In current dir
@@ -129,9 +276,9 @@ func filterFiles(entities []os.FileInfo, path string) (files []File) {
 	for _, entity := range entities {
 		if !entity.IsDir() {
 			files = append(files, File{
-				entity.Name(),
-				entity.Size(),
-				buildPath(path, entity.Name()),
+				Name: entity.Name(),
+				Size: entity.Size(),
+				Path: buildPath(path, entity.Name()),
 			})
 		}
 	}
@@ -141,4 +288,4 @@ func filterFiles(entities []os.FileInfo, path string) (files []File) {
 func buildPath(path string, name string) string {
 	path = strings.TrimSuffix(path, delimiter)
 	return path + delimiter + name
-}
\ No newline at end of file
+}
@@ -0,0 +1,431 @@
+package explorer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ERR_UNSAFE_PATH       = errors.New("Archive member points outside the target directory")
+	ERR_ARCHIVE_TOO_LARGE = errors.New("Archive exceeds the extraction limits")
+	ERR_TARGET_EXISTS     = errors.New("Target already exists")
+	ERR_NOT_AN_ARCHIVE    = errors.New("File is not a supported archive")
+)
+
+// ArchiveLimits protects the server from archives which unpack into
+// enormous amounts of data (zip bombs)
+type ArchiveLimits struct {
+	// MaxMemberSize is the maximum size of a single unpacked member
+	MaxMemberSize int64
+	// MaxTotalSize is the maximum size of all unpacked members
+	MaxTotalSize int64
+	// MaxMembers is the maximum number of members in archive
+	MaxMembers int
+	// MaxRatio is the maximum allowed ratio between unpacked and packed size
+	MaxRatio int64
+}
+
+// DefaultArchiveLimits are used when no other limits are configured
+var DefaultArchiveLimits = ArchiveLimits{
+	MaxMemberSize: 1 << 30,
+	MaxTotalSize:  4 << 30,
+	MaxMembers:    100000,
+	MaxRatio:      200,
+}
+
+// Progress is called while archive operations run with the amount of
+// processed and total bytes
+type Progress func(done int64, total int64)
+
+// Extract unpacks the archive into the target directory. The target
+// directory is created and must not exist yet
+func (explorer *Explorer) Extract(archive string, target string, limits ArchiveLimits, progress Progress) (err error) {
+	if err = explorer.Check(archive, Read); err != nil {
+		return
+	}
+	if err = explorer.CheckTree(target, Write); err != nil {
+		return
+	}
+	if _, member, ok := archiveLocation(archive); !ok || member != "" {
+		return ERR_NOT_AN_ARCHIVE
+	}
+	if _, err = os.Stat(target); err == nil {
+		return ERR_TARGET_EXISTS
+	}
+	if err = os.MkdirAll(target, 0755); err != nil {
+		return
+	}
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		err = extractZip(archive, target, limits, progress)
+	} else {
+		err = extractTar(archive, target, limits, progress)
+	}
+	if err != nil {
+		os.RemoveAll(target)
+	}
+	return
+}
+
+func extractZip(archive string, target string, limits ArchiveLimits, progress Progress) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return ERR_CANNOT_READ
+	}
+	defer reader.Close()
+	if len(reader.File) > limits.MaxMembers {
+		return ERR_ARCHIVE_TOO_LARGE
+	}
+	// Declared sizes are checked up front, the real sizes are enforced
+	// while copying as headers can lie
+	var total int64
+	for _, file := range reader.File {
+		size := int64(file.UncompressedSize64)
+		if size > limits.MaxMemberSize {
+			return ERR_ARCHIVE_TOO_LARGE
+		}
+		if file.CompressedSize64 > 0 && size/int64(file.CompressedSize64) > limits.MaxRatio {
+			return ERR_ARCHIVE_TOO_LARGE
+		}
+		total += size
+	}
+	if total > limits.MaxTotalSize {
+		return ERR_ARCHIVE_TOO_LARGE
+	}
+	counter := &extractCounter{limits: limits, total: total, progress: progress}
+	for _, file := range reader.File {
+		destination, err := memberDestination(target, file.Name)
+		if err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err = os.MkdirAll(destination, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			return ERR_CANNOT_READ
+		}
+		err = counter.write(destination, content)
+		content.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractTar(archive string, target string, limits ArchiveLimits, progress Progress) error {
+	info, err := os.Stat(archive)
+	if err != nil {
+		return ERR_CANNOT_READ
+	}
+	entries, err := readTar(archive)
+	if err != nil {
+		return ERR_CANNOT_READ
+	}
+	var total int64
+	for _, entry := range entries {
+		total += entry.size
+	}
+	if total > limits.MaxTotalSize {
+		return ERR_ARCHIVE_TOO_LARGE
+	}
+	reader, closer, err := openTar(archive)
+	if err != nil {
+		return ERR_CANNOT_READ
+	}
+	defer closer.Close()
+	// Tar members have no packed size of their own, so the size of the
+	// whole archive is used for the ratio check
+	counter := &extractCounter{
+		limits:   limits,
+		total:    total,
+		maxTotal: info.Size() * limits.MaxRatio,
+		progress: progress,
+	}
+	members := 0
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return ERR_CANNOT_READ
+		}
+		if members++; members > limits.MaxMembers {
+			return ERR_ARCHIVE_TOO_LARGE
+		}
+		destination, err := memberDestination(target, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(destination, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if header.Size > limits.MaxMemberSize {
+				return ERR_ARCHIVE_TOO_LARGE
+			}
+			if err = counter.write(destination, reader); err != nil {
+				return err
+			}
+		}
+		// Links and special files are skipped, they could point
+		// outside the target directory
+	}
+}
+
+// memberDestination resolves the member name inside the target directory
+// and rejects names which escape it (zip slip)
+func memberDestination(target string, name string) (destination string, err error) {
+	name = strings.Replace(name, "\\", "/", -1)
+	if strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" {
+		return "", ERR_UNSAFE_PATH
+	}
+	destination = filepath.Join(target, filepath.FromSlash(name))
+	relative, err := filepath.Rel(target, destination)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", ERR_UNSAFE_PATH
+	}
+	return destination, nil
+}
+
+// extractCounter tracks the amount of unpacked data across members
+type extractCounter struct {
+	limits   ArchiveLimits
+	done     int64
+	total    int64
+	maxTotal int64
+	progress Progress
+}
+
+func (counter *extractCounter) write(destination string, content io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	limited := io.LimitReader(content, counter.limits.MaxMemberSize+1)
+	written, err := io.Copy(file, &progressReader{limited, counter})
+	if err != nil {
+		return err
+	}
+	if written > counter.limits.MaxMemberSize {
+		return ERR_ARCHIVE_TOO_LARGE
+	}
+	return nil
+}
+
+func (counter *extractCounter) add(n int64) error {
+	counter.done += n
+	if counter.done > counter.limits.MaxTotalSize {
+		return ERR_ARCHIVE_TOO_LARGE
+	}
+	if counter.maxTotal > 0 && counter.done > counter.maxTotal {
+		return ERR_ARCHIVE_TOO_LARGE
+	}
+	if counter.progress != nil {
+		counter.progress(counter.done, counter.total)
+	}
+	return nil
+}
+
+type progressReader struct {
+	reader  io.Reader
+	counter *extractCounter
+}
+
+func (reader *progressReader) Read(p []byte) (n int, err error) {
+	n, err = reader.reader.Read(p)
+	if n > 0 {
+		if countErr := reader.counter.add(int64(n)); countErr != nil {
+			return n, countErr
+		}
+	}
+	return
+}
+
+// Pack creates a new zip or tar.gz archive at target containing the
+// provided files and directories
+func (explorer *Explorer) Pack(paths []string, target string, progress Progress) (err error) {
+	if !IsArchive(target) {
+		return ERR_NOT_AN_ARCHIVE
+	}
+	if err = explorer.Check(target, Write); err != nil {
+		return
+	}
+	var total int64
+	for _, path := range paths {
+		if err = explorer.CheckTree(path, Read); err != nil {
+			return
+		}
+		size, sizeErr := treeSize(path)
+		if sizeErr != nil {
+			return ERR_CANNOT_READ
+		}
+		total += size
+	}
+	if _, err = os.Stat(target); err == nil {
+		return ERR_TARGET_EXISTS
+	}
+	temp := target + ".part"
+	file, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return
+	}
+	counter := &extractCounter{
+		limits:   ArchiveLimits{MaxTotalSize: math.MaxInt64},
+		total:    total,
+		progress: progress,
+	}
+	if strings.HasSuffix(strings.ToLower(target), ".zip") {
+		err = packZip(file, paths, counter)
+	} else {
+		err = packTar(file, target, paths, counter)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp, target)
+	}
+	if err != nil {
+		os.Remove(temp)
+	}
+	return
+}
+
+type packWalker func(source string, name string, info os.FileInfo) error
+
+// walkSelection visits every file and directory of the selection except
+// skip, the archive being written. Names are relative to the parent
+// directory of each selected entity
+func walkSelection(paths []string, skip string, visit packWalker) error {
+	for _, path := range paths {
+		base := filepath.Dir(filepath.Clean(path))
+		err := filepath.Walk(path, func(source string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if filepath.Clean(source) == filepath.Clean(skip) {
+				return nil
+			}
+			name, err := filepath.Rel(base, source)
+			if err != nil {
+				return err
+			}
+			return visit(source, filepath.ToSlash(name), info)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func packZip(file *os.File, paths []string, counter *extractCounter) error {
+	writer := zip.NewWriter(file)
+	err := walkSelection(paths, file.Name(), func(source string, name string, info os.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+			_, err = writer.CreateHeader(header)
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		header.Method = zip.Deflate
+		member, err := writer.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return copyMember(member, source, counter)
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func packTar(file *os.File, target string, paths []string, counter *extractCounter) error {
+	var output io.Writer = file
+	var gzipWriter *gzip.Writer
+	name := strings.ToLower(target)
+	if strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz") {
+		gzipWriter = gzip.NewWriter(file)
+		output = gzipWriter
+	}
+	writer := tar.NewWriter(output)
+	err := walkSelection(paths, file.Name(), func(source string, name string, info os.FileInfo) error {
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err = writer.WriteHeader(header); err != nil || info.IsDir() {
+			return err
+		}
+		return copyMember(writer, source, counter)
+	})
+	if err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	if gzipWriter != nil {
+		return gzipWriter.Close()
+	}
+	return nil
+}
+
+func copyMember(writer io.Writer, source string, counter *extractCounter) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(writer, &progressReader{file, counter})
+	return err
+}
+
+func treeSize(path string) (size int64, err error) {
+	err = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return
+}
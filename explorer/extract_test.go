@@ -0,0 +1,120 @@
+package explorer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Extract_ShouldUnpackZipIntoTargetDirectory(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createZip("rootDir/delivery.zip", archiveMembers)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+	var lastDone, lastTotal int64
+
+	err := explorer.Extract("rootDir/delivery.zip", "rootDir/delivery", DefaultArchiveLimits,
+		func(done int64, total int64) {
+			lastDone, lastTotal = done, total
+		})
+
+	content, _ := ioutil.ReadFile("rootDir/delivery/docs/manual.txt")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "manual", string(content))
+	assert.Equal(t, int64(14), lastDone)
+	assert.Equal(t, int64(14), lastTotal)
+}
+
+func Test_Extract_ShouldUnpackTarGzIntoTargetDirectory(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createTarGz("rootDir/delivery.tar.gz", archiveMembers)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	err := explorer.Extract("rootDir/delivery.tar.gz", "rootDir/delivery", DefaultArchiveLimits, nil)
+
+	content, _ := ioutil.ReadFile("rootDir/delivery/readme.txt")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "hello", string(content))
+}
+
+func Test_Extract_ShouldRejectMembersOutsideTheTarget(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createZip("rootDir/evil.zip", map[string]string{"../evil.txt": "evil"})
+	createTarGz("rootDir/evil.tar.gz", map[string]string{"/etc/evil.txt": "evil"})
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	zipErr := explorer.Extract("rootDir/evil.zip", "rootDir/zip", DefaultArchiveLimits, nil)
+	tarErr := explorer.Extract("rootDir/evil.tar.gz", "rootDir/tar", DefaultArchiveLimits, nil)
+
+	assert.Equal(t, ERR_UNSAFE_PATH, zipErr)
+	assert.Equal(t, ERR_UNSAFE_PATH, tarErr)
+	_, statErr := os.Stat("rootDir/evil.txt")
+	assert.True(t, os.IsNotExist(statErr))
+	_, statErr = os.Stat("rootDir/zip")
+	assert.True(t, os.IsNotExist(statErr))
+}
+
+func Test_Extract_ShouldRejectArchivesExceedingLimits(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createZip("rootDir/bomb.zip", map[string]string{"bomb.txt": strings.Repeat("0", 100000)})
+	createTarGz("rootDir/big.tar.gz", map[string]string{"big.txt": strings.Repeat("0", 1000)})
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+	limits := DefaultArchiveLimits
+	limits.MaxRatio = 10
+	sizeLimits := DefaultArchiveLimits
+	sizeLimits.MaxMemberSize = 100
+
+	ratioErr := explorer.Extract("rootDir/bomb.zip", "rootDir/bomb", limits, nil)
+	sizeErr := explorer.Extract("rootDir/big.tar.gz", "rootDir/big", sizeLimits, nil)
+
+	assert.Equal(t, ERR_ARCHIVE_TOO_LARGE, ratioErr)
+	assert.Equal(t, ERR_ARCHIVE_TOO_LARGE, sizeErr)
+}
+
+func Test_Extract_ShouldReturnErrorIfTargetExists(t *testing.T) {
+	os.MkdirAll("rootDir/delivery", 0777)
+	createZip("rootDir/delivery.zip", archiveMembers)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	err := explorer.Extract("rootDir/delivery.zip", "rootDir/delivery", DefaultArchiveLimits, nil)
+
+	assert.Equal(t, ERR_TARGET_EXISTS, err)
+}
+
+func Test_Pack_ShouldCreateArchiveWithSelectedEntities(t *testing.T) {
+	os.MkdirAll("rootDir/dir1/sub", 0777)
+	ioutil.WriteFile("rootDir/dir1/sub/file1.txt", []byte("file1"), 0777)
+	ioutil.WriteFile("rootDir/file2.txt", []byte("file2"), 0777)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+	selection := []string{"rootDir/dir1", "rootDir/file2.txt"}
+
+	zipErr := explorer.Pack(selection, "rootDir/selection.zip", nil)
+	tarErr := explorer.Pack(selection, "rootDir/selection.tar.gz", nil)
+
+	assert.Equal(t, nil, zipErr)
+	assert.Equal(t, nil, tarErr)
+	for _, archive := range []string{"rootDir/selection.zip", "rootDir/selection.tar.gz"} {
+		reader, _, err := explorer.Open(archive + "!/dir1/sub/file1.txt")
+		assert.Equal(t, nil, err)
+		content, _ := ioutil.ReadAll(reader)
+		reader.Close()
+		assert.Equal(t, "file1", string(content))
+		files, _ := explorer.Files(archive)
+		assert.Equal(t, "file2.txt", files[0].Name)
+	}
+}
+
+func Test_Pack_ShouldReturnErrorIfTargetIsOutsideTheRoot(t *testing.T) {
+	explorer := New("rootDir")
+
+	err := explorer.Pack([]string{"rootDir/file.txt"}, "otherDir/selection.zip", nil)
+
+	assert.Equal(t, ERR_OUT_OF_ROOT, err)
+}
@@ -1,8 +1,36 @@
 package explorer
 
-// File structure stores metadata of file
+import (
+	"path"
+	"strings"
+	"time"
+)
+
+// ImageExtensions are the extensions of images, which are shown with
+// thumbnails
+var ImageExtensions = []string{".jpg", ".jpeg", ".png", ".gif"}
+
+// File structure stores metadata of file. ModTime is only filled for
+// archive members and opened files
 type File struct {
-	Name string
-	Size int64
-	Path string
+	Name    string
+	Size    int64
+	Path    string
+	ModTime time.Time
+}
+
+// IsImage checks if the file is an image with thumbnail support
+func (file File) IsImage() bool {
+	return IsImage(file.Name)
+}
+
+// IsImage checks if the file name has one of the image extensions
+func IsImage(name string) bool {
+	extension := strings.ToLower(path.Ext(name))
+	for _, imageExtension := range ImageExtensions {
+		if extension == imageExtension {
+			return true
+		}
+	}
+	return false
 }
\ No newline at end of file
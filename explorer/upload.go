@@ -0,0 +1,56 @@
+package explorer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ERR_INVALID_NAME = errors.New("File name is not valid")
+	ERR_EXISTS       = errors.New("File already exists")
+	ERR_TOO_LARGE    = errors.New("File is too large")
+)
+
+// CreateFile stores the content read from the reader as a new file in the
+// directory. Existing files are never overwritten and content larger than
+// maxSize is rejected. Zero maxSize means no limit
+func (explorer *Explorer) CreateFile(dir string, name string, reader io.Reader, maxSize int64) (file File, err error) {
+	if err = explorer.Check(dir, Write); err != nil {
+		return
+	}
+	if _, _, ok := archiveLocation(dir); ok {
+		return file, ERR_CANNOT_WRITE
+	}
+	name = filepath.Base(strings.Replace(name, "\\", "/", -1))
+	if name == "." || name == "/" || name == ".." || strings.HasPrefix(name, ".") {
+		return file, ERR_INVALID_NAME
+	}
+	path := buildPath(dir, name)
+	osFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return file, ERR_EXISTS
+	}
+	if err != nil {
+		return file, ERR_CANNOT_WRITE
+	}
+	if maxSize > 0 {
+		reader = io.LimitReader(reader, maxSize+1)
+	}
+	size, err := io.Copy(osFile, reader)
+	if closeErr := osFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && maxSize > 0 && size > maxSize {
+		err = ERR_TOO_LARGE
+	} else if err != nil {
+		err = ERR_CANNOT_WRITE
+	}
+	if err != nil {
+		os.Remove(path)
+		return
+	}
+	return File{Name: name, Size: size, Path: path}, nil
+}
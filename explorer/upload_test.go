@@ -0,0 +1,43 @@
+package explorer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_CreateFile_ShouldStoreFileInDirectory(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	file, err := explorer.CreateFile("rootDir", "../../report.txt", strings.NewReader("content"), 100)
+
+	content, _ := ioutil.ReadFile("rootDir/report.txt")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, File{Name: "report.txt", Size: 7, Path: "rootDir/report.txt"}, file)
+	assert.Equal(t, "content", string(content))
+}
+
+func Test_CreateFile_ShouldRejectExistingLargeAndHiddenFiles(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	ioutil.WriteFile("rootDir/report.txt", []byte("old"), 0777)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	_, existsErr := explorer.CreateFile("rootDir", "report.txt", strings.NewReader("new"), 100)
+	_, largeErr := explorer.CreateFile("rootDir", "large.txt", strings.NewReader("too large"), 5)
+	_, hiddenErr := explorer.CreateFile("rootDir", ".htaccess", strings.NewReader("x"), 100)
+	_, outErr := explorer.CreateFile("otherDir", "report.txt", strings.NewReader("x"), 100)
+
+	content, _ := ioutil.ReadFile("rootDir/report.txt")
+	_, statErr := os.Stat("rootDir/large.txt")
+	assert.Equal(t, ERR_EXISTS, existsErr)
+	assert.Equal(t, ERR_TOO_LARGE, largeErr)
+	assert.Equal(t, ERR_INVALID_NAME, hiddenErr)
+	assert.Equal(t, ERR_OUT_OF_ROOT, outErr)
+	assert.Equal(t, "old", string(content))
+	assert.True(t, os.IsNotExist(statErr))
+}
@@ -0,0 +1,79 @@
+package explorer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+var (
+	ERR_CONFLICT     = errors.New("File was changed since it was loaded")
+	ERR_CANNOT_WRITE = errors.New("Cannot write this file")
+)
+
+// Version returns a token which changes whenever the file is modified.
+// It is built from the modification time and the size of the file
+func (explorer *Explorer) Version(path string) (version string, err error) {
+	if err = explorer.Check(path, Read); err != nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		err = ERR_NOT_FOUND
+		return
+	}
+	return fileVersion(info), nil
+}
+
+// WriteFile replaces the file content if the file still has the provided
+// version. The content is written to a temporary file first and renamed
+// over the original, so readers never see a partially written file
+func (explorer *Explorer) WriteFile(path string, content []byte, version string) (err error) {
+	if err = explorer.Check(path, Write); err != nil {
+		return
+	}
+	if _, _, ok := archiveLocation(path); ok {
+		return ERR_CANNOT_WRITE
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return ERR_NOT_FOUND
+	}
+	if fileVersion(info) != version {
+		return ERR_CONFLICT
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return ERR_CANNOT_WRITE
+	}
+	_, err = temp.Write(content)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), info.Mode())
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return ERR_CANNOT_WRITE
+	}
+	// The file could have been changed while the new content was written
+	if current, statErr := os.Stat(path); statErr != nil || fileVersion(current) != version {
+		os.Remove(temp.Name())
+		return ERR_CONFLICT
+	}
+	if err = os.Rename(temp.Name(), path); err != nil {
+		os.Remove(temp.Name())
+		return ERR_CANNOT_WRITE
+	}
+	return
+}
+
+func fileVersion(info os.FileInfo) string {
+	return strconv.FormatInt(info.ModTime().UnixNano(), 36) + "-" + strconv.FormatInt(info.Size(), 36)
+}
@@ -0,0 +1,58 @@
+package explorer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_WriteFile_ShouldReplaceContentIfVersionMatches(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	ioutil.WriteFile("rootDir/config.ini", []byte("old"), 0640)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+	version, _ := explorer.Version("rootDir/config.ini")
+
+	err := explorer.WriteFile("rootDir/config.ini", []byte("new content"), version)
+
+	content, _ := ioutil.ReadFile("rootDir/config.ini")
+	info, _ := os.Stat("rootDir/config.ini")
+	newVersion, _ := explorer.Version("rootDir/config.ini")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "new content", string(content))
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	assert.NotEqual(t, version, newVersion)
+}
+
+func Test_WriteFile_ShouldReturnConflictIfFileChanged(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	ioutil.WriteFile("rootDir/config.ini", []byte("old"), 0777)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+	version, _ := explorer.Version("rootDir/config.ini")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes("rootDir/config.ini", later, later)
+
+	err := explorer.WriteFile("rootDir/config.ini", []byte("new"), version)
+
+	content, _ := ioutil.ReadFile("rootDir/config.ini")
+	files, _ := ioutil.ReadDir("rootDir")
+	assert.Equal(t, ERR_CONFLICT, err)
+	assert.Equal(t, "old", string(content))
+	assert.Equal(t, 1, len(files))
+}
+
+func Test_WriteFile_ShouldRejectArchiveMembersAndPathsOutsideTheRoot(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	createZip("rootDir/delivery.zip", archiveMembers)
+	defer os.RemoveAll("rootDir")
+	explorer := New("rootDir")
+
+	archiveErr := explorer.WriteFile("rootDir/delivery.zip!/readme.txt", []byte("new"), "")
+	rootErr := explorer.WriteFile("otherDir/file.txt", []byte("new"), "")
+
+	assert.Equal(t, ERR_CANNOT_WRITE, archiveErr)
+	assert.Equal(t, ERR_OUT_OF_ROOT, rootErr)
+}
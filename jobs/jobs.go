@@ -0,0 +1,155 @@
+// jobs package runs long operations in the background and keeps track
+// of their progress
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	// maxFinished is the number of finished jobs kept for reporting
+	maxFinished = 100
+)
+
+// Job is a snapshot of a background operation state. Owner is the user
+// who started the job, empty without authentication
+type Job struct {
+	ID       string    `json:"id"`
+	Owner    string    `json:"owner,omitempty"`
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	Done     int64     `json:"done"`
+	Total    int64     `json:"total"`
+	Error    string    `json:"error,omitempty"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished,omitempty"`
+}
+
+// Percent returns the job progress in percents
+func (job Job) Percent() int {
+	if job.Status == StatusDone {
+		return 100
+	}
+	if job.Total <= 0 {
+		return 0
+	}
+	return int(job.Done * 100 / job.Total)
+}
+
+// Task is a function executed by a job. It reports its progress through
+// the provided function
+type Task func(progress func(done int64, total int64)) error
+
+// Manager starts jobs and stores their states
+type Manager struct {
+	mutex sync.Mutex
+	jobs  map[string]*Job
+}
+
+// NewManager returns a new instance of Manager
+func NewManager() *Manager {
+	return &Manager{jobs: map[string]*Job{}}
+}
+
+// Start runs the task of the owner in a new goroutine and returns the
+// created job
+func (manager *Manager) Start(owner string, name string, task Task) Job {
+	job := &Job{
+		ID:      newID(),
+		Owner:   owner,
+		Name:    name,
+		Status:  StatusRunning,
+		Started: time.Now(),
+	}
+	manager.mutex.Lock()
+	manager.jobs[job.ID] = job
+	manager.prune()
+	snapshot := *job
+	manager.mutex.Unlock()
+
+	go func() {
+		err := task(func(done int64, total int64) {
+			manager.mutex.Lock()
+			job.Done = done
+			job.Total = total
+			manager.mutex.Unlock()
+		})
+		manager.mutex.Lock()
+		defer manager.mutex.Unlock()
+		job.Finished = time.Now()
+		job.Status = StatusDone
+		if err != nil {
+			job.Status = StatusFailed
+			job.Error = err.Error()
+		}
+	}()
+	return snapshot
+}
+
+// Get returns the job with the provided id
+func (manager *Manager) Get(id string) (job Job, ok bool) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	stored, ok := manager.jobs[id]
+	if ok {
+		job = *stored
+	}
+	return
+}
+
+// List returns all known jobs, the most recent first
+func (manager *Manager) List() (jobs []Job) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	for _, job := range manager.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Started.After(jobs[j].Started)
+	})
+	return
+}
+
+// Active returns the number of running jobs
+func (manager *Manager) Active() (active int) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	for _, job := range manager.jobs {
+		if job.Status == StatusRunning {
+			active++
+		}
+	}
+	return
+}
+
+// prune forgets the oldest finished jobs. Must be called under the lock
+func (manager *Manager) prune() {
+	var finished []*Job
+	for _, job := range manager.jobs {
+		if job.Status != StatusRunning {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Finished.Before(finished[j].Finished)
+	})
+	for _, job := range finished[:len(finished)-maxFinished] {
+		delete(manager.jobs, job.ID)
+	}
+}
+
+func newID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func waitFor(manager *Manager, id string) Job {
+	for i := 0; i < 100; i++ {
+		if job, _ := manager.Get(id); job.Status != StatusRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	job, _ := manager.Get(id)
+	return job
+}
+
+func Test_Start_ShouldRunTaskAndReportProgress(t *testing.T) {
+	manager := NewManager()
+
+	job := manager.Start("", "dummy job", func(progress func(int64, int64)) error {
+		progress(5, 10)
+		return nil
+	})
+	finished := waitFor(manager, job.ID)
+
+	assert.Equal(t, "dummy job", finished.Name)
+	assert.Equal(t, StatusDone, finished.Status)
+	assert.Equal(t, int64(5), finished.Done)
+	assert.Equal(t, int64(10), finished.Total)
+	assert.Equal(t, 100, finished.Percent())
+}
+
+func Test_Start_ShouldStoreTaskError(t *testing.T) {
+	manager := NewManager()
+
+	job := manager.Start("", "dummy job", func(progress func(int64, int64)) error {
+		return errors.New("dummy error")
+	})
+	finished := waitFor(manager, job.ID)
+
+	assert.Equal(t, StatusFailed, finished.Status)
+	assert.Equal(t, "dummy error", finished.Error)
+}
+
+func Test_Percent_ShouldReturnProgressOfRunningJob(t *testing.T) {
+	job := Job{Status: StatusRunning, Done: 25, Total: 100}
+
+	assert.Equal(t, 25, job.Percent())
+}
+
+func Test_List_ShouldReturnMostRecentJobsFirst(t *testing.T) {
+	manager := NewManager()
+	block := make(chan bool)
+	first := manager.Start("", "first", func(progress func(int64, int64)) error {
+		<-block
+		return nil
+	})
+	time.Sleep(time.Millisecond)
+	second := manager.Start("", "second", func(progress func(int64, int64)) error {
+		<-block
+		return nil
+	})
+
+	jobs := manager.List()
+	active := manager.Active()
+	close(block)
+
+	assert.Equal(t, second.ID, jobs[0].ID)
+	assert.Equal(t, first.ID, jobs[1].ID)
+	assert.Equal(t, 2, active)
+}
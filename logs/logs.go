@@ -0,0 +1,166 @@
+// logs package configures the output of all loggers and logs messages of
+// HTTP requests with their request IDs
+package logs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/op/go-logging"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ERR_UNKNOWN_FORMAT = errors.New("Log format must be text or json")
+	ERR_UNKNOWN_LEVEL  = errors.New("Log level must be one of debug, info, notice, warning, error and critical")
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var textFormatter = logging.MustStringFormatter("%{level:.4s} %{module}: %{message}")
+
+var installBackend sync.Once
+
+// backend is the backend of all loggers. Configure swaps its output and
+// level while other goroutines are logging
+var backend = new(switchBackend)
+
+// Configure writes the messages of all loggers to out in the format, text
+// by default, leaving out messages below the level, info by default
+func Configure(out io.Writer, format string, level string) error {
+	parsedLevel, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	if err = CheckFormat(format); err != nil {
+		return err
+	}
+	var output logging.Backend
+	if format == FormatJSON {
+		output = logging.NewBackendFormatter(logging.NewLogBackend(out, "", 0), jsonFormatter{})
+	} else {
+		output = logging.NewBackendFormatter(logging.NewLogBackend(out, "", log.LstdFlags), textFormatter)
+	}
+	backend.current.Store(switchState{output, parsedLevel})
+	installBackend.Do(func() {
+		logging.SetBackend(backend)
+	})
+	return nil
+}
+
+// ParseLevel parses the name of a log level. Empty means info
+func ParseLevel(level string) (logging.Level, error) {
+	if level == "" {
+		return logging.INFO, nil
+	}
+	parsed, err := logging.LogLevel(level)
+	if err != nil {
+		return parsed, ERR_UNKNOWN_LEVEL
+	}
+	return parsed, nil
+}
+
+// CheckFormat checks the name of a log format. Empty means text
+func CheckFormat(format string) error {
+	if format != "" && format != FormatText && format != FormatJSON {
+		return ERR_UNKNOWN_FORMAT
+	}
+	return nil
+}
+
+type switchState struct {
+	output logging.Backend
+	level  logging.Level
+}
+
+// switchBackend passes records to the current output, if they have at
+// least the current level
+type switchBackend struct {
+	current atomic.Value
+}
+
+func (backend *switchBackend) state() switchState {
+	return backend.current.Load().(switchState)
+}
+
+func (backend *switchBackend) Log(level logging.Level, calldepth int, record *logging.Record) error {
+	return backend.state().output.Log(level, calldepth+1, record)
+}
+
+func (backend *switchBackend) GetLevel(module string) logging.Level {
+	return backend.state().level
+}
+
+func (backend *switchBackend) SetLevel(level logging.Level, module string) {
+	backend.current.Store(switchState{backend.state().output, level})
+}
+
+func (backend *switchBackend) IsEnabledFor(level logging.Level, module string) bool {
+	return level <= backend.state().level
+}
+
+// Fields are logged as key=value pairs in text format and as keys of the
+// object in JSON format
+type Fields map[string]interface{}
+
+func (fields Fields) String() string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for index, key := range keys {
+		pairs[index] = fmt.Sprintf("%v=%v", key, fields[key])
+	}
+	return strings.Join(pairs, " ")
+}
+
+// jsonFormatter writes every record as a JSON object. Request IDs and
+// fields become keys of the object, a message of only fields has no
+// message key
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(calldepth int, record *logging.Record, w io.Writer) error {
+	object := map[string]interface{}{
+		"time":   record.Time.Format(time.RFC3339Nano),
+		"level":  record.Level.String(),
+		"module": record.Module,
+	}
+	args := record.Args
+	message := record.Message()
+	if len(args) > 0 {
+		if id, ok := args[0].(requestID); ok {
+			object["request_id"] = string(id)
+			message = strings.TrimPrefix(message, id.String())
+			args = args[1:]
+		}
+	}
+	if len(args) == 1 {
+		if fields, ok := args[0].(Fields); ok {
+			for key, value := range fields {
+				object[key] = value
+			}
+			message = ""
+		}
+	}
+	if message != "" {
+		object["message"] = message
+	}
+	line, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(line)
+	return err
+}
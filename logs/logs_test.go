@@ -0,0 +1,64 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/op/go-logging"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testLogger = logging.MustGetLogger("Test")
+
+func Test_Configure_ShouldWriteJSONWithRequestID(t *testing.T) {
+	out := new(bytes.Buffer)
+	Configure(out, FormatJSON, "info")
+	r := WithRequestID(httptest.NewRequest("GET", "/", nil), "abc")
+
+	Request(r, testLogger).Warningf("Rejected %v", "token")
+	Request(r, testLogger).Infof("%v", Fields{"status": 200})
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var warning, fields map[string]interface{}
+	json.Unmarshal([]byte(lines[0]), &warning)
+	json.Unmarshal([]byte(lines[1]), &fields)
+
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, "Rejected token", warning["message"])
+	assert.Equal(t, "abc", warning["request_id"])
+	assert.Equal(t, "WARNING", warning["level"])
+	assert.Equal(t, "Test", warning["module"])
+	assert.Equal(t, float64(200), fields["status"])
+	assert.Equal(t, nil, fields["message"])
+}
+
+func Test_Configure_ShouldLeaveOutMessagesBelowLevel(t *testing.T) {
+	out := new(bytes.Buffer)
+	Configure(out, FormatText, "warning")
+	r := WithRequestID(httptest.NewRequest("GET", "/", nil), "abc")
+
+	testLogger.Infof("Started")
+	Request(r, testLogger).Errorf("Failed %v", 1)
+
+	assert.NotContains(t, out.String(), "Started")
+	assert.Contains(t, out.String(), "ERRO Test: [abc] Failed 1")
+}
+
+func Test_Configure_ShouldRejectUnknownSettings(t *testing.T) {
+	assert.Equal(t, ERR_UNKNOWN_FORMAT, Configure(new(bytes.Buffer), "xml", ""))
+	assert.Equal(t, ERR_UNKNOWN_LEVEL, Configure(new(bytes.Buffer), "", "verbose"))
+}
+
+func Test_Fields_ShouldBeSortedInText(t *testing.T) {
+	assert.Equal(t, "bytes=5 method=GET", Fields{"method": "GET", "bytes": 5}.String())
+}
+
+func Test_User_ShouldBeSharedByRequestCopies(t *testing.T) {
+	r := WithRequestID(httptest.NewRequest("GET", "/", nil), "abc")
+
+	SetUser(r.WithContext(r.Context()), "alice")
+
+	assert.Equal(t, "alice", User(r))
+	assert.Equal(t, "", User(httptest.NewRequest("GET", "/", nil)))
+}
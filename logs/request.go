@@ -0,0 +1,106 @@
+package logs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/op/go-logging"
+	"net/http"
+	"sync"
+)
+
+// RequestIDHeader is the response header with the ID of the request
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey struct{}
+
+// request is what the log knows about a request
+type request struct {
+	id    string
+	mutex sync.Mutex
+	user  string
+}
+
+// requestID is passed as the first argument of messages of a request
+type requestID string
+
+func (id requestID) String() string {
+	return "[" + string(id) + "] "
+}
+
+// NewRequestID returns a random request ID
+func NewRequestID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// WithRequestID returns the request with the ID, which is added to the
+// messages of loggers returned by Request
+func WithRequestID(r *http.Request, id string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, &request{id: id}))
+}
+
+// RequestID returns the ID of the request. It is empty for requests
+// without an ID
+func RequestID(r *http.Request) string {
+	if request, ok := r.Context().Value(contextKey{}).(*request); ok {
+		return request.id
+	}
+	return ""
+}
+
+// SetUser records the logged in user for the access log, which is written
+// outside of the authentication
+func SetUser(r *http.Request, user string) {
+	if request, ok := r.Context().Value(contextKey{}).(*request); ok {
+		request.mutex.Lock()
+		request.user = user
+		request.mutex.Unlock()
+	}
+}
+
+// User returns the user recorded with SetUser
+func User(r *http.Request) string {
+	if request, ok := r.Context().Value(contextKey{}).(*request); ok {
+		request.mutex.Lock()
+		defer request.mutex.Unlock()
+		return request.user
+	}
+	return ""
+}
+
+// Logger logs messages of a request prefixed with the request ID
+type Logger struct {
+	logger *logging.Logger
+	id     requestID
+}
+
+// Request returns the logger for messages of the request
+func Request(r *http.Request, logger *logging.Logger) Logger {
+	return Logger{logger, requestID(RequestID(r))}
+}
+
+func (logger Logger) args(args []interface{}) []interface{} {
+	return append([]interface{}{logger.id}, args...)
+}
+
+// Debugf logs a message using DEBUG as log level
+func (logger Logger) Debugf(format string, args ...interface{}) {
+	logger.logger.Debugf("%v"+format, logger.args(args)...)
+}
+
+// Infof logs a message using INFO as log level
+func (logger Logger) Infof(format string, args ...interface{}) {
+	logger.logger.Infof("%v"+format, logger.args(args)...)
+}
+
+// Warningf logs a message using WARNING as log level
+func (logger Logger) Warningf(format string, args ...interface{}) {
+	logger.logger.Warningf("%v"+format, logger.args(args)...)
+}
+
+// Errorf logs a message using ERROR as log level
+func (logger Logger) Errorf(format string, args ...interface{}) {
+	logger.logger.Errorf("%v"+format, logger.args(args)...)
+}
@@ -0,0 +1,253 @@
+// metrics package collects counters and histograms and writes them in the
+// Prometheus text exposition format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the upper bounds of histogram buckets for durations
+// in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry of the metrics created with the package
+// functions
+var Default = new(Registry)
+
+// metric is a counter or a histogram with all its series
+type metric interface {
+	write(w io.Writer) error
+}
+
+// Registry keeps metrics in the order they were created
+type Registry struct {
+	mutex   sync.Mutex
+	metrics []metric
+}
+
+// Write writes all metrics of the registry
+func (registry *Registry) Write(w io.Writer) error {
+	registry.mutex.Lock()
+	metrics := append([]metric{}, registry.metrics...)
+	registry.mutex.Unlock()
+	for _, metric := range metrics {
+		if err := metric.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (registry *Registry) register(metric metric) {
+	registry.mutex.Lock()
+	registry.metrics = append(registry.metrics, metric)
+	registry.mutex.Unlock()
+}
+
+// family is what counters and histograms have in common: a name, a help
+// text and series identified by label values
+type family struct {
+	name   string
+	help   string
+	labels []string
+	series map[string][]string
+}
+
+func newFamily(name string, help string, labels []string) family {
+	return family{name: name, help: help, labels: labels, series: map[string][]string{}}
+}
+
+// key returns the key of the series of the label values. Must be called
+// under the lock
+func (family *family) key(values []string) string {
+	if len(values) != len(family.labels) {
+		panic(fmt.Sprintf("%v has %v labels, got %v values", family.name, len(family.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	if _, ok := family.series[key]; !ok {
+		family.series[key] = append([]string{}, values...)
+	}
+	return key
+}
+
+// keys returns the keys of all series in a stable order. Must be called
+// under the lock
+func (family *family) keys() []string {
+	keys := make([]string, 0, len(family.series))
+	for key := range family.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// labelPairs formats the labels of the series with extra pairs appended
+func (family *family) labelPairs(key string, extra ...string) string {
+	var pairs []string
+	for index, value := range family.series[key] {
+		pairs = append(pairs, family.labels[index]+`="`+escape(value)+`"`)
+	}
+	pairs = append(pairs, extra...)
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (family *family) header(w io.Writer, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", family.name, family.help, family.name, kind)
+	return err
+}
+
+// Counter is a value which only goes up, like the number of requests
+type Counter struct {
+	family
+	mutex  sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter creates a counter in the default registry. Values of the
+// labels are passed to Add
+func NewCounter(name string, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewCounter creates a counter in the registry
+func (registry *Registry) NewCounter(name string, help string, labels ...string) *Counter {
+	counter := &Counter{family: newFamily(name, help, labels), values: map[string]float64{}}
+	if len(labels) == 0 {
+		counter.values[counter.key(nil)] = 0
+	}
+	registry.register(counter)
+	return counter
+}
+
+// Add adds the value to the series of the label values
+func (counter *Counter) Add(value float64, labelValues ...string) {
+	counter.mutex.Lock()
+	counter.values[counter.key(labelValues)] += value
+	counter.mutex.Unlock()
+}
+
+// Inc adds one to the series of the label values
+func (counter *Counter) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Value returns the value of the series of the label values
+func (counter *Counter) Value(labelValues ...string) float64 {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	return counter.values[strings.Join(labelValues, "\xff")]
+}
+
+func (counter *Counter) write(w io.Writer) error {
+	counter.mutex.Lock()
+	defer counter.mutex.Unlock()
+	if err := counter.header(w, "counter"); err != nil {
+		return err
+	}
+	for _, key := range counter.keys() {
+		if _, err := fmt.Fprintf(w, "%v%v %v\n", counter.name, counter.labelPairs(key), format(counter.values[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Histogram counts observed values, like durations, in buckets
+type Histogram struct {
+	family
+	mutex   sync.Mutex
+	buckets []float64
+	counts  map[string][]uint64
+	sums    map[string]float64
+	totals  map[string]uint64
+}
+
+// NewHistogram creates a histogram in the default registry. Buckets are
+// the upper bounds of the buckets in increasing order
+func NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// NewHistogram creates a histogram in the registry
+func (registry *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	histogram := &Histogram{
+		family:  newFamily(name, help, labels),
+		buckets: buckets,
+		counts:  map[string][]uint64{},
+		sums:    map[string]float64{},
+		totals:  map[string]uint64{},
+	}
+	registry.register(histogram)
+	return histogram
+}
+
+// Observe adds the value to the series of the label values
+func (histogram *Histogram) Observe(value float64, labelValues ...string) {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	key := histogram.key(labelValues)
+	counts, ok := histogram.counts[key]
+	if !ok {
+		counts = make([]uint64, len(histogram.buckets))
+		histogram.counts[key] = counts
+	}
+	for index, bound := range histogram.buckets {
+		if value <= bound {
+			counts[index]++
+		}
+	}
+	histogram.sums[key] += value
+	histogram.totals[key]++
+}
+
+func (histogram *Histogram) write(w io.Writer) error {
+	histogram.mutex.Lock()
+	defer histogram.mutex.Unlock()
+	if err := histogram.header(w, "histogram"); err != nil {
+		return err
+	}
+	for _, key := range histogram.keys() {
+		for index, bound := range histogram.buckets {
+			if _, err := fmt.Fprintf(w, "%v_bucket%v %v\n", histogram.name,
+				histogram.labelPairs(key, `le="`+format(bound)+`"`), histogram.counts[key][index]); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%v_bucket%v %v\n%v_sum%v %v\n%v_count%v %v\n",
+			histogram.name, histogram.labelPairs(key, `le="+Inf"`), histogram.totals[key],
+			histogram.name, histogram.labelPairs(key), format(histogram.sums[key]),
+			histogram.name, histogram.labelPairs(key), histogram.totals[key])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteValue writes a metric without labels whose value is only known
+// when the metrics are collected, like the number of active jobs. Kind is
+// "counter" or "gauge"
+func WriteValue(w io.Writer, kind string, name string, help string, value float64) error {
+	_, err := fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n%v %v\n", name, help, name, kind, name, format(value))
+	return err
+}
+
+func format(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Registry_ShouldWriteCounters(t *testing.T) {
+	registry := new(Registry)
+	plain := registry.NewCounter("scanned_total", "Directories scanned")
+	labeled := registry.NewCounter("requests_total", "Requests", "route", "status")
+	plain.Add(2)
+	labeled.Inc("/scan/{dir}/", "200")
+	labeled.Inc("/scan/{dir}/", "200")
+	labeled.Inc(`/a"b`, "404")
+	out := new(bytes.Buffer)
+
+	err := registry.Write(out)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `# HELP scanned_total Directories scanned
+# TYPE scanned_total counter
+scanned_total 2
+# HELP requests_total Requests
+# TYPE requests_total counter
+requests_total{route="/a\"b",status="404"} 1
+requests_total{route="/scan/{dir}/",status="200"} 2
+`, out.String())
+	assert.Equal(t, float64(2), labeled.Value("/scan/{dir}/", "200"))
+}
+
+func Test_Registry_ShouldWriteCumulativeHistogramBuckets(t *testing.T) {
+	registry := new(Registry)
+	histogram := registry.NewHistogram("duration_seconds", "Durations", []float64{0.1, 1}, "route")
+	histogram.Observe(0.05, "/")
+	histogram.Observe(0.5, "/")
+	histogram.Observe(2, "/")
+	out := new(bytes.Buffer)
+
+	registry.Write(out)
+
+	assert.Equal(t, `# HELP duration_seconds Durations
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="/",le="0.1"} 1
+duration_seconds_bucket{route="/",le="1"} 2
+duration_seconds_bucket{route="/",le="+Inf"} 3
+duration_seconds_sum{route="/"} 2.55
+duration_seconds_count{route="/"} 3
+`, out.String())
+}
+
+func Test_Counter_ShouldPanicOnWrongLabelValues(t *testing.T) {
+	counter := new(Registry).NewCounter("requests_total", "Requests", "route")
+
+	assert.Panics(t, func() { counter.Inc() })
+}
@@ -0,0 +1,70 @@
+// pathref package provides strategies for turning paths into URL segments
+// and back. Deployments choose between readable URLs and secret paths
+package pathref
+
+import (
+	"errors"
+	"github.com/doojin/file-explorer/crypto"
+	"strings"
+)
+
+var (
+	ERR_OUT_OF_ROOT       = errors.New("Path is outside the root directory")
+	ERR_MALFORMED_REF     = errors.New("Path reference is not valid")
+	ERR_UNKNOWN_REFERENCE = errors.New("Path reference is unknown")
+)
+
+// Strategy turns paths into references which can be used as a single URL
+// path segment, and references back into paths. References which were
+// not created by the strategy must be rejected
+type Strategy interface {
+	Encode(path string) (ref string, err error)
+	Decode(ref string) (path string, err error)
+}
+
+// Binder is implemented by strategies whose references can be bound to a
+// session. References of a bound strategy are only accepted by a strategy
+// bound to the same session
+type Binder interface {
+	Bind(session string) Strategy
+}
+
+// Encrypted references are encrypted paths. They hide the paths, but are
+// long and unreadable
+type Encrypted struct {
+	encoder crypto.Encoder
+	session string
+}
+
+// NewEncrypted returns a new instance of Encrypted
+func NewEncrypted(encoder crypto.Encoder) *Encrypted {
+	return &Encrypted{encoder: encoder}
+}
+
+// Bind returns a copy of the strategy which encrypts paths bound to the
+// session
+func (encrypted *Encrypted) Bind(session string) Strategy {
+	return &Encrypted{encoder: encrypted.encoder, session: session}
+}
+
+// Encode encrypts the path
+func (encrypted *Encrypted) Encode(path string) (string, error) {
+	return encrypted.encoder.EncryptBound(path, encrypted.session)
+}
+
+// Decode decrypts the reference
+func (encrypted *Encrypted) Decode(ref string) (string, error) {
+	return encrypted.encoder.DecryptBound(ref, encrypted.session)
+}
+
+// relative returns the path relative to the root directory
+func relative(root string, path string) (string, bool) {
+	root = strings.TrimSuffix(root, "/")
+	if path == root || path == root+"/" {
+		return "", true
+	}
+	if !strings.HasPrefix(path, root+"/") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(path, root+"/"), "/"), true
+}
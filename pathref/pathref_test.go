@@ -0,0 +1,120 @@
+package pathref
+
+import (
+	"github.com/doojin/file-explorer/crypto"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Encrypted_ShouldRoundTripPaths(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	strategy := NewEncrypted(encoder)
+
+	ref, _ := strategy.Encode("C:/share/docs")
+	path, err := strategy.Decode(ref)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "C:/share/docs", path)
+	assert.False(t, strings.Contains(ref, "share"))
+}
+
+func Test_Signed_ShouldReturnReadableRelativePaths(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	strategy := NewSigned("C:/share/", encoder)
+
+	ref, _ := strategy.Encode("C:/share/docs/report 2017.pdf")
+	rootRef, _ := strategy.Encode("C:/share")
+	path, err := strategy.Decode(ref)
+	rootPath, rootErr := strategy.Decode(rootRef)
+	_, outErr := strategy.Encode("C:/shared/docs")
+
+	assert.True(t, strings.HasPrefix(ref, "docs%2Freport%202017.pdf."), ref)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "C:/share/docs/report 2017.pdf", path)
+	assert.Equal(t, nil, rootErr)
+	assert.Equal(t, "C:/share", rootPath)
+	assert.Equal(t, ERR_OUT_OF_ROOT, outErr)
+}
+
+func Test_Signed_ShouldRejectModifiedPaths(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	strategy := NewSigned("C:/share", encoder)
+	ref, _ := strategy.Encode("C:/share/docs")
+
+	_, err := strategy.Decode(strings.Replace(ref, "docs", "..%2Fsecret", 1))
+	_, malformedErr := strategy.Decode("docs")
+
+	assert.Equal(t, crypto.ERR_INVALID_SIGNATURE, err)
+	assert.Equal(t, ERR_MALFORMED_REF, malformedErr)
+}
+
+func Test_Registry_ShouldReturnSameIDForPath(t *testing.T) {
+	registry, _ := NewRegistry("C:/share", "")
+
+	ref, _ := registry.Encode("C:/share/docs")
+	sameRef, _ := registry.Encode("C:/share/docs")
+	otherRef, _ := registry.Encode("C:/share/img")
+	path, err := registry.Decode(ref)
+	_, unknownErr := registry.Decode("unknown")
+	_, parentErr := registry.Encode("C:/")
+	_, siblingErr := registry.Encode("C:/share2/docs")
+
+	assert.Equal(t, ref, sameRef)
+	assert.NotEqual(t, ref, otherRef)
+	assert.Equal(t, 11, len(ref))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "C:/share/docs", path)
+	assert.Equal(t, ERR_UNKNOWN_REFERENCE, unknownErr)
+	assert.Equal(t, ERR_OUT_OF_ROOT, parentErr)
+	assert.Equal(t, ERR_OUT_OF_ROOT, siblingErr)
+}
+
+func Test_NewRegistry_ShouldLoadSavedIDs(t *testing.T) {
+	os.Remove("registry_test.json")
+	defer os.Remove("registry_test.json")
+	registry, _ := NewRegistry("C:/share", "registry_test.json")
+	ref, _ := registry.Encode("C:/share/docs")
+	registry.Encode("C:/share/img")
+
+	loaded, err := NewRegistry("C:/share", "registry_test.json")
+	path, decodeErr := loaded.Decode(ref)
+	sameRef, _ := loaded.Encode("C:/share/docs")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, decodeErr)
+	assert.Equal(t, "C:/share/docs", path)
+	assert.Equal(t, ref, sameRef)
+}
+
+func Test_NewRegistry_ShouldDropEntriesOutsideRoot(t *testing.T) {
+	os.Remove("registry_test.json")
+	defer os.Remove("registry_test.json")
+	ioutil.WriteFile("registry_test.json", []byte(`{"ref":"parent","path":"C:/"}`+"\n"+
+		`{"ref":"docs","path":"C:/share/docs"}`+"\n"), 0600)
+
+	registry, err := NewRegistry("C:/share", "registry_test.json")
+	_, parentErr := registry.Decode("parent")
+	content, _ := ioutil.ReadFile("registry_test.json")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ERR_UNKNOWN_REFERENCE, parentErr)
+	assert.Equal(t, `{"ref":"docs","path":"C:/share/docs"}`+"\n", string(content))
+}
+
+func Test_Encrypted_ShouldRejectReferencesOfOtherSessions(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	strategy := NewEncrypted(encoder)
+	ref, _ := strategy.Bind("session-a").Encode("C:/share")
+
+	path, err := strategy.Bind("session-a").Decode(ref)
+	_, otherErr := strategy.Bind("session-b").Decode(ref)
+	_, unboundErr := strategy.Decode(ref)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "C:/share", path)
+	assert.Equal(t, crypto.ERR_INVALID_TOKEN, otherErr)
+	assert.Equal(t, crypto.ERR_INVALID_TOKEN, unboundErr)
+}
@@ -0,0 +1,149 @@
+package pathref
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Registry references are short random IDs of paths kept on the server.
+// They are short and hide the paths, but every referenced path within the
+// root directory is remembered, so the registry grows with the number of
+// distinct paths ever listed, and links break when the registry file is
+// lost
+type Registry struct {
+	mutex sync.Mutex
+	root  string
+	file  string
+	paths map[string]string
+	refs  map[string]string
+}
+
+// entry is a line of the registry file
+type entry struct {
+	Ref  string `json:"ref"`
+	Path string `json:"path"`
+}
+
+// NewRegistry loads the registry of paths within the root directory from
+// the file. The file has an entry per line, new entries are appended to
+// it. Entries outside the root are dropped and the file is rewritten
+// without them. Registries without a file are kept in memory only
+func NewRegistry(root string, file string) (registry *Registry, err error) {
+	registry = &Registry{
+		root:  strings.TrimSuffix(root, "/"),
+		file:  file,
+		paths: map[string]string{},
+		refs:  map[string]string{},
+	}
+	if file == "" {
+		return
+	}
+	osFile, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer osFile.Close()
+	decoder := json.NewDecoder(osFile)
+	dropped := false
+	for {
+		var line entry
+		if err = decoder.Decode(&line); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := relative(registry.root, line.Path); !ok {
+			dropped = true
+			continue
+		}
+		registry.paths[line.Ref] = line.Path
+		registry.refs[line.Path] = line.Ref
+	}
+	if dropped {
+		return registry, registry.rewrite()
+	}
+	return registry, nil
+}
+
+// Encode returns the ID of the path, a new ID is created for paths which
+// weren't referenced yet. Paths outside the root directory can't be
+// referenced
+func (registry *Registry) Encode(path string) (string, error) {
+	if _, ok := relative(registry.root, path); !ok {
+		return "", ERR_OUT_OF_ROOT
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if ref, ok := registry.refs[path]; ok {
+		return ref, nil
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	ref := base64.RawURLEncoding.EncodeToString(id)
+	if err := registry.append(entry{ref, path}); err != nil {
+		return "", err
+	}
+	registry.paths[ref] = path
+	registry.refs[path] = ref
+	return ref, nil
+}
+
+// Decode returns the path of the ID
+func (registry *Registry) Decode(ref string) (string, error) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	path, ok := registry.paths[ref]
+	if !ok {
+		return "", ERR_UNKNOWN_REFERENCE
+	}
+	return path, nil
+}
+
+// append writes the entry to the file. Must be called under the lock
+func (registry *Registry) append(line entry) error {
+	if registry.file == "" {
+		return nil
+	}
+	content, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	osFile, err := os.OpenFile(registry.file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = osFile.Write(append(content, '\n'))
+	if closeErr := osFile.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// rewrite replaces the file with the current entries
+func (registry *Registry) rewrite() error {
+	var content []byte
+	for ref, path := range registry.paths {
+		line, err := json.Marshal(entry{ref, path})
+		if err != nil {
+			return err
+		}
+		content = append(append(content, line...), '\n')
+	}
+	temporary := registry.file + ".tmp"
+	if err := ioutil.WriteFile(temporary, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, registry.file)
+}
@@ -0,0 +1,65 @@
+package pathref
+
+import (
+	"encoding/base64"
+	"github.com/doojin/file-explorer/crypto"
+	"net/url"
+	"strings"
+)
+
+// signPurpose separates signatures of path references from other signed
+// tokens
+const signPurpose = "pathref"
+
+// Signed references are paths relative to the root directory followed by
+// their signature, like "docs%2Freport.pdf.MA.<signature>". They are
+// readable, but reveal the directory structure. Routers must match
+// encoded paths, otherwise "%2F" is turned into a path separator
+type Signed struct {
+	root    string
+	encoder crypto.Encoder
+}
+
+// NewSigned returns a new instance of Signed which references paths
+// within the root directory
+func NewSigned(root string, encoder crypto.Encoder) *Signed {
+	return &Signed{root: strings.TrimSuffix(root, "/"), encoder: encoder}
+}
+
+// Encode returns the signed relative path. Paths outside the root
+// directory can't be referenced
+func (signed *Signed) Encode(path string) (string, error) {
+	relativePath, ok := relative(signed.root, path)
+	if !ok {
+		return "", ERR_OUT_OF_ROOT
+	}
+	token := signed.encoder.Sign(signPurpose, []byte(relativePath))
+	// The token carries the path in base64, it is replaced with the
+	// readable path
+	signature := token[strings.Index(token, ".")+1:]
+	return url.PathEscape(relativePath) + "." + signature, nil
+}
+
+// Decode verifies the signature and returns the path
+func (signed *Signed) Decode(ref string) (string, error) {
+	signatureStart := strings.LastIndex(ref, ".")
+	if signatureStart < 0 {
+		return "", ERR_MALFORMED_REF
+	}
+	idStart := strings.LastIndex(ref[:signatureStart], ".")
+	if idStart < 0 {
+		return "", ERR_MALFORMED_REF
+	}
+	relativePath, err := url.PathUnescape(ref[:idStart])
+	if err != nil {
+		return "", ERR_MALFORMED_REF
+	}
+	payload := base64.RawURLEncoding.EncodeToString([]byte(relativePath))
+	if _, err = signed.encoder.Verify(signPurpose, payload+ref[idStart:]); err != nil {
+		return "", err
+	}
+	if relativePath == "" {
+		return signed.root, nil
+	}
+	return signed.root + "/" + relativePath, nil
+}
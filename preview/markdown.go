@@ -0,0 +1,171 @@
+package preview
+
+import (
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	orderedItemPattern = regexp.MustCompile(`^\d+[.)]\s+`)
+	rulePattern        = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	codePattern        = regexp.MustCompile("`([^`]+)`")
+	imagePattern       = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	linkPattern        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strongPattern      = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emphasisPattern    = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+)
+
+// safeSchemes are the URL schemes allowed in rendered links
+var safeSchemes = []string{"http://", "https://", "mailto:"}
+
+// Markdown renders markdown text into HTML. The source is escaped before
+// any markup is produced, so raw HTML in the document is never rendered
+// and only links with safe schemes are kept
+func Markdown(source string) template.HTML {
+	renderer := markdownRenderer{}
+	lines := strings.Split(strings.Replace(source, "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			renderer.closeBlocks()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, html.EscapeString(lines[i]))
+			}
+			renderer.write("<pre><code>" + strings.Join(code, "\n") + "</code></pre>")
+		case trimmed == "":
+			renderer.closeBlocks()
+		case headingPattern.MatchString(trimmed):
+			renderer.closeBlocks()
+			match := headingPattern.FindStringSubmatch(trimmed)
+			level := string('0' + rune(len(match[1])))
+			renderer.write("<h" + level + ">" + inline(match[2]) + "</h" + level + ">")
+		case rulePattern.MatchString(trimmed):
+			renderer.closeBlocks()
+			renderer.write("<hr>")
+		case strings.HasPrefix(trimmed, ">"):
+			renderer.open("blockquote")
+			renderer.paragraph(strings.TrimSpace(strings.TrimPrefix(trimmed, ">")))
+		case strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") || strings.HasPrefix(trimmed, "+ "):
+			renderer.open("ul")
+			renderer.write("<li>" + inline(trimmed[2:]) + "</li>")
+		case orderedItemPattern.MatchString(trimmed):
+			renderer.open("ol")
+			renderer.write("<li>" + inline(orderedItemPattern.ReplaceAllString(trimmed, "")) + "</li>")
+		case strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t"):
+			renderer.open("pre")
+			renderer.write(html.EscapeString(strings.TrimPrefix(strings.TrimPrefix(line, "\t"), "    ")) + "\n")
+		default:
+			renderer.paragraph(trimmed)
+		}
+	}
+	renderer.closeBlocks()
+	return template.HTML(renderer.output.String())
+}
+
+// markdownRenderer keeps track of the currently open block elements
+type markdownRenderer struct {
+	output      strings.Builder
+	block       string
+	inParagraph bool
+}
+
+func (renderer *markdownRenderer) write(text string) {
+	renderer.output.WriteString(text)
+}
+
+// open starts a block element unless it is already open
+func (renderer *markdownRenderer) open(block string) {
+	if renderer.block == block {
+		return
+	}
+	renderer.closeBlocks()
+	renderer.block = block
+	renderer.write("<" + block + ">")
+}
+
+func (renderer *markdownRenderer) paragraph(text string) {
+	if renderer.block == "ul" || renderer.block == "ol" || renderer.block == "pre" {
+		renderer.closeBlocks()
+	}
+	if renderer.inParagraph {
+		renderer.write(" ")
+	} else {
+		renderer.write("<p>")
+		renderer.inParagraph = true
+	}
+	renderer.write(inline(text))
+}
+
+func (renderer *markdownRenderer) closeBlocks() {
+	if renderer.inParagraph {
+		renderer.write("</p>")
+		renderer.inParagraph = false
+	}
+	if renderer.block != "" {
+		renderer.write("</" + renderer.block + ">")
+		renderer.block = ""
+	}
+}
+
+// inline renders inline markup of a line. Code spans, images and links
+// are cut out into placeholders first so that emphasis is not applied to
+// their content or URLs
+func inline(text string) string {
+	var spans []string
+	placeholder := func(span string) string {
+		spans = append(spans, span)
+		return "\x00" + strconv.Itoa(len(spans)-1) + "\x00"
+	}
+	text = codePattern.ReplaceAllStringFunc(html.EscapeString(text), func(match string) string {
+		return placeholder("<code>" + match[1:len(match)-1] + "</code>")
+	})
+	text = imagePattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := imagePattern.FindStringSubmatch(match)
+		if !isSafeURL(parts[2]) {
+			return parts[1]
+		}
+		return placeholder(`<img src="` + parts[2] + `" alt="` + parts[1] + `">`)
+	})
+	text = linkPattern.ReplaceAllStringFunc(text, func(match string) string {
+		parts := linkPattern.FindStringSubmatch(match)
+		if !isSafeURL(parts[2]) {
+			return parts[1]
+		}
+		return placeholder(`<a href="` + parts[2] + `" rel="nofollow noopener">` + emphasis(parts[1]) + `</a>`)
+	})
+	text = emphasis(text)
+	// Later spans can contain earlier ones, e.g. code inside a link
+	for i := len(spans) - 1; i >= 0; i-- {
+		text = strings.Replace(text, "\x00"+strconv.Itoa(i)+"\x00", spans[i], 1)
+	}
+	return text
+}
+
+func emphasis(text string) string {
+	text = strongPattern.ReplaceAllString(text, "<strong>$1$2</strong>")
+	return emphasisPattern.ReplaceAllString(text, "<em>$1$2</em>")
+}
+
+// isSafeURL allows absolute URLs with safe schemes and relative URLs
+func isSafeURL(escapedURL string) bool {
+	url := strings.ToLower(strings.TrimSpace(html.UnescapeString(escapedURL)))
+	for _, scheme := range safeSchemes {
+		if strings.HasPrefix(url, scheme) {
+			return true
+		}
+	}
+	// A colon before any path separator means an unknown scheme
+	colon := strings.Index(url, ":")
+	if colon < 0 {
+		return true
+	}
+	separator := strings.IndexAny(url, "/?#")
+	return separator >= 0 && separator < colon
+}
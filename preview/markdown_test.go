@@ -0,0 +1,44 @@
+package preview
+
+import (
+	"github.com/stretchr/testify/assert"
+	"html/template"
+	"testing"
+)
+
+func Test_Markdown_ShouldRenderBlocks(t *testing.T) {
+	source := "# Title\n\nFirst line\nsecond line\n\n- one\n- two\n\n1. first\n\n```\ncode <b>\n```\n\n> quote\n\n---"
+
+	expected := "<h1>Title</h1>" +
+		"<p>First line second line</p>" +
+		"<ul><li>one</li><li>two</li></ul>" +
+		"<ol><li>first</li></ol>" +
+		"<pre><code>code &lt;b&gt;</code></pre>" +
+		"<blockquote><p>quote</p></blockquote>" +
+		"<hr>"
+	assert.Equal(t, template.HTML(expected), Markdown(source))
+}
+
+func Test_Markdown_ShouldRenderInlineMarkup(t *testing.T) {
+	source := "**bold** *em* `a*b*c` [link](https://example.com/a_b_c) snake_case_name"
+
+	expected := "<p><strong>bold</strong> <em>em</em> <code>a*b*c</code> " +
+		"<a href=\"https://example.com/a_b_c\" rel=\"nofollow noopener\">link</a> snake_case_name</p>"
+	assert.Equal(t, template.HTML(expected), Markdown(source))
+}
+
+func Test_Markdown_ShouldEscapeRawHTMLAndUnsafeLinks(t *testing.T) {
+	source := "<script>alert(1)</script> [click](javascript:alert) ![x](JaVaScRiPt:alert)"
+
+	expected := "<p>&lt;script&gt;alert(1)&lt;/script&gt; click x</p>"
+	assert.Equal(t, template.HTML(expected), Markdown(source))
+}
+
+func Test_isSafeURL_ShouldAllowOnlySafeSchemes(t *testing.T) {
+	assert.True(t, isSafeURL("https://example.com"))
+	assert.True(t, isSafeURL("mailto:someone@example.com"))
+	assert.True(t, isSafeURL("docs/readme.md"))
+	assert.True(t, isSafeURL("./a:b"))
+	assert.False(t, isSafeURL("javascript:alert(1)"))
+	assert.False(t, isSafeURL("data:text/html,hi"))
+}
@@ -0,0 +1,174 @@
+// preview package detects how a file can be shown in the browser and
+// prepares its content for displaying
+package preview
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kinds of previews
+const (
+	KindText     = "text"
+	KindMarkdown = "markdown"
+	KindImage    = "image"
+	KindPDF      = "pdf"
+	KindAudio    = "audio"
+	KindVideo    = "video"
+	KindBinary   = "binary"
+)
+
+// MaxTextSize is the amount of text shown before the preview is truncated
+const MaxTextSize = 256 * 1024
+
+// sniffSize is the amount of content used for content type detection
+const sniffSize = 512
+
+var extensionKinds = map[string]string{
+	".md":       KindMarkdown,
+	".markdown": KindMarkdown,
+	".png":      KindImage,
+	".jpg":      KindImage,
+	".jpeg":     KindImage,
+	".gif":      KindImage,
+	".bmp":      KindImage,
+	".webp":     KindImage,
+	".svg":      KindImage,
+	".ico":      KindImage,
+	".pdf":      KindPDF,
+	".mp3":      KindAudio,
+	".wav":      KindAudio,
+	".ogg":      KindAudio,
+	".flac":     KindAudio,
+	".m4a":      KindAudio,
+	".mp4":      KindVideo,
+	".webm":     KindVideo,
+	".ogv":      KindVideo,
+	".mov":      KindVideo,
+}
+
+// languages maps file extensions to syntax highlighting languages
+var languages = map[string]string{
+	".go":   "go",
+	".js":   "javascript",
+	".ts":   "typescript",
+	".py":   "python",
+	".rb":   "ruby",
+	".java": "java",
+	".c":    "c",
+	".h":    "c",
+	".cpp":  "cpp",
+	".cs":   "csharp",
+	".php":  "php",
+	".rs":   "rust",
+	".sh":   "bash",
+	".sql":  "sql",
+	".html": "html",
+	".xml":  "xml",
+	".css":  "css",
+	".json": "json",
+	".yaml": "yaml",
+	".yml":  "yaml",
+	".toml": "ini",
+	".ini":  "ini",
+	".conf": "ini",
+	".csv":  "plaintext",
+	".txt":  "plaintext",
+}
+
+// Preview contains everything needed to show a file
+type Preview struct {
+	Kind      string
+	Language  string
+	Text      string
+	Truncated bool
+}
+
+// Kind detects the kind of the file using its name and the beginning
+// of its content
+func Kind(name string, head []byte) string {
+	if kind, ok := extensionKinds[strings.ToLower(path.Ext(name))]; ok {
+		return kind
+	}
+	contentType := http.DetectContentType(head)
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return KindImage
+	case strings.HasPrefix(contentType, "audio/"):
+		return KindAudio
+	case strings.HasPrefix(contentType, "video/"):
+		return KindVideo
+	case contentType == "application/pdf":
+		return KindPDF
+	}
+	if isText(head) {
+		return KindText
+	}
+	return KindBinary
+}
+
+// Language returns the syntax highlighting language for the file name
+func Language(name string) string {
+	return languages[strings.ToLower(path.Ext(name))]
+}
+
+// Read prepares the preview of the file content. Text content is read
+// up to MaxTextSize, other kinds are shown by the browser directly
+func Read(name string, reader io.Reader) (preview Preview, err error) {
+	content, err := ioutil.ReadAll(io.LimitReader(reader, MaxTextSize+1))
+	if err != nil {
+		return
+	}
+	head := content
+	if len(head) > sniffSize {
+		head = head[:sniffSize]
+	}
+	preview.Kind = Kind(name, head)
+	if preview.Kind != KindText && preview.Kind != KindMarkdown {
+		return
+	}
+	if len(content) > MaxTextSize {
+		content = content[:MaxTextSize]
+		preview.Truncated = true
+	}
+	content = trimIncompleteRune(content)
+	if !isText(content) {
+		preview.Kind = KindBinary
+		return
+	}
+	// Truncated text is cut at the last complete line
+	if preview.Truncated {
+		if i := strings.LastIndex(string(content), "\n"); i > 0 {
+			content = content[:i+1]
+		}
+	}
+	preview.Text = string(content)
+	preview.Language = Language(name)
+	return
+}
+
+// isText checks if the content looks like UTF-8 text
+func isText(content []byte) bool {
+	content = trimIncompleteRune(content)
+	for _, b := range content {
+		if b == 0 {
+			return false
+		}
+	}
+	return utf8.Valid(content)
+}
+
+// trimIncompleteRune removes a rune cut in the middle at the end of content
+func trimIncompleteRune(content []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(content); i++ {
+		r, size := utf8.DecodeLastRune(content[:len(content)-i+1])
+		if r != utf8.RuneError || size > 1 {
+			return content[:len(content)-i+1]
+		}
+	}
+	return content
+}
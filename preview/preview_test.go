@@ -0,0 +1,62 @@
+package preview
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Kind_ShouldDetectKindByExtension(t *testing.T) {
+	assert.Equal(t, KindMarkdown, Kind("README.md", nil))
+	assert.Equal(t, KindImage, Kind("photo.JPG", nil))
+	assert.Equal(t, KindPDF, Kind("manual.pdf", nil))
+	assert.Equal(t, KindAudio, Kind("song.mp3", nil))
+	assert.Equal(t, KindVideo, Kind("movie.mp4", nil))
+}
+
+func Test_Kind_ShouldDetectKindByContent(t *testing.T) {
+	assert.Equal(t, KindImage, Kind("noextension", []byte("\x89PNG\x0D\x0A\x1A\x0A")))
+	assert.Equal(t, KindText, Kind("Makefile", []byte("all:\n\tgo build")))
+	assert.Equal(t, KindBinary, Kind("program.exe", []byte("MZ\x00\x01\x02")))
+}
+
+func Test_Language_ShouldReturnHighlightingLanguage(t *testing.T) {
+	assert.Equal(t, "go", Language("main.go"))
+	assert.Equal(t, "", Language("unknown.xyz"))
+}
+
+func Test_Read_ShouldReturnTextPreview(t *testing.T) {
+	preview, err := Read("main.go", strings.NewReader("package main\n"))
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Preview{
+		Kind:     KindText,
+		Language: "go",
+		Text:     "package main\n",
+	}, preview)
+}
+
+func Test_Read_ShouldTruncateLargeTextAtLineEnd(t *testing.T) {
+	line := strings.Repeat("a", 99) + "\n"
+	content := strings.Repeat(line, MaxTextSize/100+10)
+
+	preview, _ := Read("large.txt", strings.NewReader(content))
+
+	assert.True(t, preview.Truncated)
+	assert.True(t, len(preview.Text) <= MaxTextSize)
+	assert.True(t, strings.HasSuffix(preview.Text, "\n"))
+}
+
+func Test_Read_ShouldFallBackToBinaryForNonTextContent(t *testing.T) {
+	preview, _ := Read("data.txt", bytes.NewReader([]byte{'a', 0, 'b'}))
+
+	assert.Equal(t, KindBinary, preview.Kind)
+	assert.Equal(t, "", preview.Text)
+}
+
+func Test_Read_ShouldNotReadContentOfImages(t *testing.T) {
+	preview, _ := Read("photo.png", strings.NewReader("binary data"))
+
+	assert.Equal(t, Preview{Kind: KindImage}, preview)
+}
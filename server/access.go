@@ -0,0 +1,146 @@
+package server
+
+import (
+	"fmt"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/server/controller"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// accessEntry is a parsed AccessEntry
+type accessEntry struct {
+	root  string
+	rules explorer.Rules
+}
+
+// accessPolicy resolves the explorer of every user from the access
+// configuration
+type accessPolicy struct {
+	root     string
+	readOnly []string
+	users    map[string]accessEntry
+	groups   map[string]accessEntry
+}
+
+// accessPolicy parses the access configuration. It is nil when no access
+// is configured
+func (server *Server) accessPolicy() (*accessPolicy, error) {
+	config := server.Config.Access
+	if len(config.Users) == 0 && len(config.Groups) == 0 {
+		return nil, nil
+	}
+	policy := &accessPolicy{
+		root:     strings.TrimSuffix(server.Config.RootDir, "/"),
+		readOnly: server.readOnlyPaths(),
+		users:    map[string]accessEntry{},
+		groups:   map[string]accessEntry{},
+	}
+	for _, kind := range []struct {
+		name    string
+		entries []AccessEntry
+		parsed  map[string]accessEntry
+	}{{"user", config.Users, policy.users}, {"group", config.Groups, policy.groups}} {
+		for _, entry := range kind.entries {
+			if entry.Name == "" {
+				return nil, fmt.Errorf("%v without name", kind.name)
+			}
+			if _, ok := kind.parsed[entry.Name]; ok {
+				return nil, fmt.Errorf("%v %v is listed twice", kind.name, entry.Name)
+			}
+			parsed, err := parseAccessEntry(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%v %v: %v", kind.name, entry.Name, err)
+			}
+			kind.parsed[entry.Name] = parsed
+		}
+	}
+	return policy, nil
+}
+
+func parseAccessEntry(entry AccessEntry) (parsed accessEntry, err error) {
+	if entry.Root != "" {
+		if strings.Contains(entry.Root, "..") {
+			return parsed, fmt.Errorf("root %q must not contain ..", entry.Root)
+		}
+		parsed.root = path.Clean("/" + entry.Root)
+	}
+	for _, rule := range entry.Rules {
+		parsedRule := explorer.Rule{Path: path.Clean("/" + rule.Path)}
+		if parsedRule.Path == "/" {
+			parsedRule.Path = ""
+		}
+		if parsedRule.Allow, err = explorer.ParsePermissions(rule.Allow); err != nil {
+			return parsed, fmt.Errorf("rule %v: %v", rule.Path, err)
+		}
+		if parsedRule.Deny, err = explorer.ParsePermissions(rule.Deny); err != nil {
+			return parsed, fmt.Errorf("rule %v: %v", rule.Path, err)
+		}
+		parsed.rules = append(parsed.rules, parsedRule)
+	}
+	return
+}
+
+// explorer returns the explorer of the user. The second value is false
+// when neither the user nor one of the groups has an entry
+func (policy *accessPolicy) explorer(identity auth.Identity) (userExplorer explorer.Explorer, ok bool) {
+	entries := []accessEntry{}
+	if entry, found := policy.users[identity.Name]; found {
+		entries = append(entries, entry)
+	}
+	for _, group := range identity.Groups {
+		if entry, found := policy.groups[group]; found {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return userExplorer, false
+	}
+	root := ""
+	for _, entry := range entries {
+		if root == "" {
+			root = entry.root
+		}
+		userExplorer.Rules = append(userExplorer.Rules, entry.rules...)
+	}
+	userExplorer.Root = policy.root + strings.TrimSuffix(root, "/")
+	userExplorer.ReadOnly = policy.readOnly
+	userExplorer.Scanned = countScan
+	if userExplorer.Root == "" {
+		userExplorer.Root = "/"
+	}
+	return userExplorer, true
+}
+
+// resolver resolves the explorers of users who aren't logged in, like the
+// creators of share links. Groups are read from the users file
+func (policy *accessPolicy) resolver(authenticator *auth.Authenticator) controller.ExplorerResolver {
+	return func(user string) (explorer.Explorer, bool) {
+		identity := auth.Identity{Name: user}
+		if authenticator != nil {
+			identity.Groups = authenticator.Users.Groups(user)
+		}
+		return policy.explorer(identity)
+	}
+}
+
+// middleware passes the explorer of the logged in user to the controllers.
+// Requests without a user only reach public pages, which don't use it.
+// Users without access can still log out
+func (policy *accessPolicy) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.IdentityOf(r)
+		if identity.Name == "" || r.URL.Path == logoutPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userExplorer, ok := policy.explorer(identity)
+		if !ok {
+			http.Error(w, explorer.ERR_ACCESS_DENIED.Error(), 403)
+			return
+		}
+		next.ServeHTTP(w, controller.WithExplorer(r, userExplorer))
+	})
+}
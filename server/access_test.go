@@ -0,0 +1,82 @@
+package server
+
+import (
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testAccessServer() Server {
+	return Server{Config: ServerConfig{RootDir: "/srv/files", Access: AccessConfig{
+		Users: []AccessEntry{
+			{Name: "alice", Root: "home/alice", Rules: []RuleConfig{{Path: "/private", Deny: "share"}}},
+		},
+		Groups: []AccessEntry{
+			{Name: "staff", Root: "/projects/", Rules: []RuleConfig{{Path: "/", Allow: "read", Deny: "write"}}},
+			{Name: "admins", Rules: []RuleConfig{{Path: "/", Allow: "all"}}},
+		},
+	}}}
+}
+
+func Test_accessPolicy_ShouldResolveRootsAndRules(t *testing.T) {
+	server := testAccessServer()
+	policy, err := server.accessPolicy()
+
+	alice, aliceOk := policy.explorer(auth.Identity{Name: "alice", Groups: []string{"staff"}})
+	bob, bobOk := policy.explorer(auth.Identity{Name: "bob", Groups: []string{"guests", "staff"}})
+	_, eveOk := policy.explorer(auth.Identity{Name: "eve", Groups: []string{"guests"}})
+
+	assert.Equal(t, nil, err)
+	assert.True(t, aliceOk)
+	assert.Equal(t, "/srv/files/home/alice", alice.Root)
+	assert.False(t, alice.Allows("/srv/files/home/alice/private/a.txt", explorer.Share))
+	assert.False(t, alice.Allows("/srv/files/home/alice/a.txt", explorer.Write))
+	assert.True(t, bobOk)
+	assert.Equal(t, "/srv/files/projects", bob.Root)
+	assert.True(t, bob.Allows("/srv/files/projects/a.txt", explorer.Read))
+	assert.False(t, bob.Allows("/srv/files/home/alice/a.txt", explorer.Read))
+	assert.False(t, eveOk)
+}
+
+func Test_accessPolicy_ShouldRejectInvalidEntries(t *testing.T) {
+	unknownPermission := Server{Config: ServerConfig{Access: AccessConfig{
+		Users: []AccessEntry{{Name: "alice", Rules: []RuleConfig{{Path: "/", Allow: "delete"}}}},
+	}}}
+	parentRoot := Server{Config: ServerConfig{Access: AccessConfig{
+		Groups: []AccessEntry{{Name: "staff", Root: "../etc"}},
+	}}}
+	duplicate := Server{Config: ServerConfig{Access: AccessConfig{
+		Users: []AccessEntry{{Name: "alice"}, {Name: "alice"}},
+	}}}
+
+	_, unknownPermissionErr := unknownPermission.accessPolicy()
+	_, parentRootErr := parentRoot.accessPolicy()
+	_, duplicateErr := duplicate.accessPolicy()
+	policy, noAccessErr := new(Server).accessPolicy()
+
+	assert.NotEqual(t, nil, unknownPermissionErr)
+	assert.NotEqual(t, nil, parentRootErr)
+	assert.NotEqual(t, nil, duplicateErr)
+	assert.Equal(t, nil, noAccessErr)
+	assert.True(t, policy == nil)
+}
+
+func Test_accessPolicy_ShouldDenyUsersWithoutEntries(t *testing.T) {
+	server := testAccessServer()
+	policy, _ := server.accessPolicy()
+	handler := policy.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	allowed := httptest.NewRecorder()
+	denied := httptest.NewRecorder()
+	logout := httptest.NewRecorder()
+
+	handler.ServeHTTP(allowed, auth.WithIdentity(httptest.NewRequest("GET", "/", nil), auth.Identity{Name: "alice"}))
+	handler.ServeHTTP(denied, auth.WithIdentity(httptest.NewRequest("GET", "/", nil), auth.Identity{Name: "eve"}))
+	handler.ServeHTTP(logout, auth.WithIdentity(httptest.NewRequest("POST", "/logout/", nil), auth.Identity{Name: "eve"}))
+
+	assert.Equal(t, 200, allowed.Code)
+	assert.Equal(t, 403, denied.Code)
+	assert.Equal(t, 200, logout.Code)
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"fmt"
+	"github.com/doojin/file-explorer/audit"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/logs"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"time"
+)
+
+// routeActions are the actions of audited routes, by path template or by
+// method and path template. Requests of other routes are not audited. The
+// bytes of download and upload actions are counted in the metrics
+var routeActions = map[string]string{
+	"/":                          "list",
+	"/scan/{dir}/":               "list",
+	"/search/":                   "search",
+	"/download/{file}/":          "download",
+	"/raw/{file}/":               "download",
+	"/preview/{file}/":           "preview",
+	"/compare/":                  "compare",
+	"POST /edit/{file}/":         "edit",
+	"/extract/{file}/":           "extract",
+	"/pack/{dir}/":               "pack",
+	"POST /share/create/{file}/": "share",
+	"/shares/{share}/revoke/":    "revoke",
+	"/s/{token}/":                "list",
+	"/s/{token}/download/":       "download",
+	"/s/{token}/upload/":         "upload",
+	"POST " + auth.LoginPath:     "login",
+	"/logout/":                   "logout",
+}
+
+// uploadActions count the bytes received instead of the bytes sent
+var uploadActions = map[string]bool{"edit": true, "upload": true}
+
+// routeAction returns the action of the request to the route with the
+// path template. It is empty for routes which are not audited
+func routeAction(r *http.Request, template string) string {
+	if action := routeActions[r.Method+" "+template]; action != "" {
+		return action
+	}
+	return routeActions[template]
+}
+
+// auditLog opens the audit log. It is nil when no audit file is configured
+func (server *Server) auditLog() (*audit.Log, error) {
+	if server.Config.AuditFile == "" {
+		return nil, nil
+	}
+	if server.audit == nil {
+		log, err := audit.Open(server.Config.AuditFile, server.Config.AuditMaxSize, server.Config.AuditBackups)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open audit log: %v", err)
+		}
+		server.audit = log
+	}
+	return server.audit, nil
+}
+
+// auditMiddleware records the requests of audited routes of the router in
+// the log. It runs before access checks, so denied requests are recorded
+func auditMiddleware(log *audit.Log, router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := routeAction(r, routeTemplate(router, r))
+		if action == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = audit.WithEntry(r)
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+		recorder := newResponseRecorder(w)
+		next.ServeHTTP(recorder, r)
+		event := audit.Event{
+			Time:   time.Now().UTC(),
+			User:   auth.User(r),
+			IP:     auth.ClientAddress(r),
+			Action: action,
+			Result: audit.Result(recorder.status),
+			Status: recorder.status,
+			Bytes:  recorder.bytes,
+		}
+		if uploadActions[action] {
+			event.Bytes = body.bytes
+		}
+		audit.Fill(r, &event)
+		if err := log.Record(event); err != nil {
+			logs.Request(r, logger).Errorf("Cannot write audit event: %v", err)
+		}
+	})
+}
+
+// countingReader counts the bytes read from the request body
+type countingReader struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (reader *countingReader) Read(content []byte) (int, error) {
+	read, err := reader.ReadCloser.Read(content)
+	reader.bytes += int64(read)
+	return read, err
+}
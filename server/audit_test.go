@@ -0,0 +1,53 @@
+package server
+
+import (
+	"github.com/doojin/file-explorer/audit"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_auditMiddleware_ShouldRecordAuditedRoutes(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "audit")
+	defer os.RemoveAll(dir)
+	log, _ := audit.Open(filepath.Join(dir, "audit.log"), 0, 0)
+	defer log.Close()
+	router := mux.NewRouter()
+	router.HandleFunc("/download/{file}/", func(w http.ResponseWriter, r *http.Request) {
+		audit.AddPath(r, "/srv/a.txt")
+		w.Write([]byte("content"))
+	})
+	router.HandleFunc("/s/{token}/upload/", func(w http.ResponseWriter, r *http.Request) {
+		audit.SetUser(r, "share 1")
+		ioutil.ReadAll(r.Body)
+		http.Error(w, "Uploads are not allowed", 403)
+	}).Methods("POST")
+	router.HandleFunc("/jobs/", func(w http.ResponseWriter, r *http.Request) {})
+	handler := auditMiddleware(log, router, router)
+
+	download := auth.WithIdentity(httptest.NewRequest("GET", "/download/token/", nil), auth.Identity{Name: "alice"})
+	download.RemoteAddr = "192.0.2.1:50000"
+	handler.ServeHTTP(httptest.NewRecorder(), download)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/s/token/upload/", strings.NewReader("12345")))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/jobs/", nil))
+	events, _ := log.Recent(audit.Filter{}, 10)
+
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, "upload", events[0].Action)
+	assert.Equal(t, "share 1", events[0].User)
+	assert.Equal(t, audit.ResultDenied, events[0].Result)
+	assert.Equal(t, int64(5), events[0].Bytes)
+	assert.Equal(t, "download", events[1].Action)
+	assert.Equal(t, "alice", events[1].User)
+	assert.Equal(t, "192.0.2.1", events[1].IP)
+	assert.Equal(t, "/srv/a.txt", events[1].Path)
+	assert.Equal(t, audit.ResultOK, events[1].Result)
+	assert.Equal(t, int64(7), events[1].Bytes)
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultConfigFile is loaded when no configuration file is chosen with
+// the -config flag. It is optional, everything can be set by environment
+// variables and flags as well
+const DefaultConfigFile = "conf.xml"
+
+// EnvPrefix starts the names of environment variables overriding
+// configuration settings, like FILE_EXPLORER_PORT
+const EnvPrefix = "FILE_EXPLORER_"
+
+const redacted = "<redacted>"
+
+// configField is a setting which can be overridden by an environment
+// variable and a flag
+type configField struct {
+	// name is the JSON and YAML name, nested names are joined by dots:
+	// "tls.cert"
+	name  string
+	env   string
+	index []int
+	kind  reflect.Kind
+}
+
+// LoadConfig builds the configuration from its layers. Later layers
+// override earlier ones:
+//
+//  1. the configuration file chosen by -config (conf.xml by default), in
+//     the format given by its extension: .xml, .json, .yaml or .yml
+//  2. environment variables, like FILE_EXPLORER_PORT or
+//     FILE_EXPLORER_TLS_CERT
+//  3. flags, like -port or -tls.cert
+//
+// Lists like keys are given as JSON in environment variables and flags
+func (server *Server) LoadConfig(args []string, environ []string) error {
+	flags := flag.NewFlagSet("file-explorer", flag.ContinueOnError)
+	configFile := flags.String("config", DefaultConfigFile, "configuration file, .xml, .json, .yaml or .yml")
+	fields := configFields()
+	values := map[string]string{}
+	for _, field := range fields {
+		flags.Var(&overrideFlag{field.name, field.kind == reflect.Bool, values},
+			field.name, "overrides "+field.name+", also set by "+field.env)
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	configChosen := false
+	flags.Visit(func(chosen *flag.Flag) {
+		configChosen = configChosen || chosen.Name == "config"
+	})
+	server.args, server.environ = args, environ
+	if _, err := os.Stat(*configFile); err == nil || configChosen {
+		if err = server.LoadConfigFile(*configFile); err != nil {
+			return err
+		}
+		server.configFile = *configFile
+		if info, err := os.Stat(*configFile); err == nil {
+			server.configModTime = info.ModTime()
+		}
+	}
+	env := map[string]string{}
+	for _, variable := range environ {
+		if parts := strings.SplitN(variable, "=", 2); len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	for _, field := range fields {
+		if value, ok := env[field.env]; ok {
+			if err := server.override(field, value); err != nil {
+				return fmt.Errorf("%v: %v", field.env, err)
+			}
+		}
+	}
+	for _, field := range fields {
+		if value, ok := values[field.name]; ok {
+			if err := server.override(field, value); err != nil {
+				return fmt.Errorf("-%v: %v", field.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// LoadConfigFile loads the configuration file in the format given by its
+// extension
+func (server *Server) LoadConfigFile(filename string) error {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xml":
+		return server.LoadXMLConfig(filename)
+	case ".json":
+		return server.LoadJSONConfig(filename)
+	case ".yaml", ".yml":
+		return server.LoadYAMLConfig(filename)
+	}
+	return fmt.Errorf("%v: unknown configuration format, use .xml, .json, .yaml or .yml", filename)
+}
+
+// PrintConfig writes the effective configuration as JSON. Keys and
+// passphrases are redacted
+func (server *Server) PrintConfig(w io.Writer) error {
+	config := server.Config
+	if config.Key != "" {
+		config.Key = redacted
+	}
+	if config.Passphrase != "" {
+		config.Passphrase = redacted
+	}
+	config.Keys = append([]KeyConfig(nil), config.Keys...)
+	for i := range config.Keys {
+		config.Keys[i].Value = redacted
+	}
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+// override sets the field from its string form
+func (server *Server) override(field configField, value string) error {
+	target := reflect.ValueOf(&server.Config).Elem().FieldByIndex(field.index)
+	switch field.kind {
+	case reflect.String:
+		target.SetString(value)
+	case reflect.Int, reflect.Int64:
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		target.SetInt(number)
+	case reflect.Bool:
+		flag, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		target.SetBool(flag)
+	default:
+		decoder := json.NewDecoder(strings.NewReader(value))
+		decoder.DisallowUnknownFields()
+		parsed := reflect.New(target.Type())
+		if err := decoder.Decode(parsed.Interface()); err != nil {
+			return fmt.Errorf("not valid JSON: %v", err)
+		}
+		target.Set(parsed.Elem())
+	}
+	return nil
+}
+
+// configFields lists the settings of ServerConfig. Fields of nested
+// structs are listed one by one
+func configFields() (fields []configField) {
+	var collect func(structType reflect.Type, name string, env string, index []int)
+	collect = func(structType reflect.Type, name string, env string, index []int) {
+		for i := 0; i < structType.NumField(); i++ {
+			field := structType.Field(i)
+			jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+			if jsonName == "" || jsonName == "-" {
+				continue
+			}
+			fieldIndex := append(append([]int(nil), index...), i)
+			if field.Type.Kind() == reflect.Struct {
+				collect(field.Type, name+jsonName+".", env+envName(jsonName)+"_", fieldIndex)
+				continue
+			}
+			fields = append(fields, configField{
+				name:  name + jsonName,
+				env:   env + envName(jsonName),
+				index: fieldIndex,
+				kind:  field.Type.Kind(),
+			})
+		}
+	}
+	collect(reflect.TypeOf(ServerConfig{}), "", EnvPrefix, nil)
+	return
+}
+
+// envName turns a JSON name like thumbnailCacheSize into THUMBNAIL_CACHE_SIZE
+func envName(name string) string {
+	var result []rune
+	for i, char := range name {
+		if unicode.IsUpper(char) && i > 0 {
+			result = append(result, '_')
+		}
+		result = append(result, unicode.ToUpper(char))
+	}
+	return string(result)
+}
+
+// overrideFlag records the flag value, it is applied after the
+// configuration file and the environment
+type overrideFlag struct {
+	name   string
+	isBool bool
+	values map[string]string
+}
+
+func (value *overrideFlag) String() string {
+	return ""
+}
+
+func (value *overrideFlag) Set(flag string) error {
+	value.values[value.name] = flag
+	return nil
+}
+
+func (value *overrideFlag) IsBoolFlag() bool {
+	return value.isBool
+}
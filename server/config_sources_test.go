@@ -0,0 +1,120 @@
+package server
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_LoadConfig_ShouldApplyFileThenEnvironmentThenFlags(t *testing.T) {
+	createConfigFile("config.json", "{\"port\":1234,\"root\":\"/srv\",\"goroutineLevels\":2}")
+	defer deleteConfigFile("config.json")
+	server := Server{}
+
+	err := server.LoadConfig(
+		[]string{"-config", "config.json", "-port", "8080", "-tls.enabled"},
+		[]string{"FILE_EXPLORER_PORT=9090", "FILE_EXPLORER_GOROUTINE_LEVELS=3", "FILE_EXPLORER_TLS_CERT=cert.pem", "PATH=/bin"})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 8080, server.Config.Port)
+	assert.Equal(t, "/srv", server.Config.RootDir)
+	assert.Equal(t, 3, server.Config.GoroutineLevels)
+	assert.Equal(t, "cert.pem", server.Config.TLS.Cert)
+	assert.True(t, server.Config.TLS.Enabled)
+}
+
+func Test_LoadConfig_ShouldOverrideYAMLFileByEnvironment(t *testing.T) {
+	createConfigFile("config.yaml", "port: 1234\nroot: /srv\nreadTimeout: 30s\ntls:\n  cert: file.pem\n")
+	defer deleteConfigFile("config.yaml")
+	server := Server{}
+
+	err := server.LoadConfig([]string{"-config", "config.yaml"},
+		[]string{"FILE_EXPLORER_READ_TIMEOUT=1m", "FILE_EXPLORER_TLS_CERT=env.pem"})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1234, server.Config.Port)
+	assert.Equal(t, "/srv", server.Config.RootDir)
+	assert.Equal(t, "1m", server.Config.ReadTimeout)
+	assert.Equal(t, "env.pem", server.Config.TLS.Cert)
+}
+
+func Test_configFields_ShouldHaveSameYAMLAndJSONNames(t *testing.T) {
+	var check func(configType reflect.Type)
+	check = func(configType reflect.Type) {
+		for index := 0; index < configType.NumField(); index++ {
+			field := configType.Field(index)
+			assert.Equal(t, field.Tag.Get("json"), field.Tag.Get("yaml"), field.Name)
+			if field.Type.Kind() == reflect.Slice {
+				field.Type = field.Type.Elem()
+			}
+			if field.Type.Kind() == reflect.Struct {
+				check(field.Type)
+			}
+		}
+	}
+
+	check(reflect.TypeOf(ServerConfig{}))
+}
+
+func Test_LoadConfig_ShouldParseListsAsJSON(t *testing.T) {
+	server := Server{}
+
+	err := server.LoadConfig([]string{"-config", "missing.xml"}, nil)
+	assert.NotEqual(t, nil, err)
+
+	err = server.LoadConfig(nil, []string{`FILE_EXPLORER_KEYS=[{"id":"1","key":"k3J9xQ2mP7vR4tW8","active":true}]`})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []KeyConfig{{ID: "1", Value: "k3J9xQ2mP7vR4tW8", Active: true}}, server.Config.Keys)
+
+	err = server.LoadConfig([]string{"-keys", `[{"name":"1"}]`}, nil)
+	assert.NotEqual(t, nil, err)
+}
+
+func Test_LoadConfig_ShouldRejectMalformedValues(t *testing.T) {
+	server := Server{}
+
+	envErr := server.LoadConfig(nil, []string{"FILE_EXPLORER_PORT=http"})
+	flagErr := server.LoadConfig([]string{"-deterministicTokens=maybe"}, nil)
+
+	assert.True(t, strings.Contains(envErr.Error(), "FILE_EXPLORER_PORT"))
+	assert.True(t, strings.Contains(flagErr.Error(), "deterministicTokens"))
+}
+
+func Test_LoadConfigFile_ShouldDetectFormatByExtension(t *testing.T) {
+	createConfigFile("config.json", "{\"port\":1234}")
+	defer deleteConfigFile("config.json")
+	server := Server{}
+
+	err := server.LoadConfigFile("config.json")
+	unknownErr := server.LoadConfigFile("config.ini")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1234, server.Config.Port)
+	assert.NotEqual(t, nil, unknownErr)
+}
+
+func Test_PrintConfig_ShouldRedactSecrets(t *testing.T) {
+	server := Server{Config: ServerConfig{
+		Port:       1234,
+		Key:        "k3J9xQ2mP7vR4tW8",
+		Passphrase: "correct horse battery",
+		Keys:       []KeyConfig{{ID: "1", Value: "Zp5Lq8Nc1Vb6Hs0D"}},
+	}}
+	output := new(bytes.Buffer)
+
+	err := server.PrintConfig(output)
+
+	assert.Equal(t, nil, err)
+	assert.True(t, strings.Contains(output.String(), "\"port\": 1234"))
+	assert.False(t, strings.Contains(output.String(), "k3J9xQ2mP7vR4tW8"))
+	assert.False(t, strings.Contains(output.String(), "correct horse"))
+	assert.False(t, strings.Contains(output.String(), "Zp5Lq8Nc1Vb6Hs0D"))
+	assert.Equal(t, "Zp5Lq8Nc1Vb6Hs0D", server.Config.Keys[0].Value)
+}
+
+func Test_envName_ShouldSplitWords(t *testing.T) {
+	assert.Equal(t, "THUMBNAIL_CACHE_SIZE", envName("thumbnailCacheSize"))
+	assert.Equal(t, "ROOT", envName("root"))
+}
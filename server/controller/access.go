@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"context"
+	"github.com/doojin/file-explorer/explorer"
+	"net/http"
+)
+
+type explorerKey struct{}
+
+// WithExplorer returns the request with the explorer of its user, which
+// has the root and the access rules of the user
+func WithExplorer(r *http.Request, userExplorer explorer.Explorer) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), explorerKey{}, userExplorer))
+}
+
+// ExplorerResolver returns the explorer of the user by name. The second
+// value is false when the user has no access
+type ExplorerResolver func(user string) (explorer.Explorer, bool)
+
+// userExplorer returns the explorer of the user of the request. Without
+// users the explorer of the controller is used
+func userExplorer(r *http.Request, fallback explorer.Explorer) *explorer.Explorer {
+	if found, ok := r.Context().Value(explorerKey{}).(explorer.Explorer); ok {
+		return &found
+	}
+	return &fallback
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"encoding/json"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/jobs"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/gorilla/mux"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+)
+
+const current_job = "job"
+
+type archiveController struct {
+	paths    pathref.Strategy
+	explorer explorer.Explorer
+	jobs     *jobs.Manager
+	limits   explorer.ArchiveLimits
+}
+
+// NewArchiveController creates a new instance of archiveController
+func NewArchiveController(paths pathref.Strategy, explorer explorer.Explorer,
+	jobs *jobs.Manager, limits explorer.ArchiveLimits) (controller archiveController) {
+	controller.paths = paths
+	controller.explorer = explorer
+	controller.jobs = jobs
+	controller.limits = limits
+	return
+}
+
+// ExtractHandler starts extraction of an archive into a new directory
+// next to it
+func (controller *archiveController) ExtractHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	archive, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	target := path.Join(path.Dir(archive), archiveBaseName(archive))
+	if name := r.FormValue("target-name"); name != "" {
+		target = path.Join(path.Dir(archive), path.Base(name))
+	}
+	userRoot := userExplorer(r, controller.explorer)
+	job := controller.jobs.Start(auth.User(r), "Extract "+path.Base(archive), func(progress func(int64, int64)) error {
+		return userRoot.Extract(archive, target, controller.limits, progress)
+	})
+	http.Redirect(w, r, "/jobs/#"+job.ID, 303)
+}
+
+// PackHandler starts packing of the selected entities into a new archive
+// inside the current directory
+func (controller *archiveController) PackHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	currentDir, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_dir])
+	if !ok {
+		return
+	}
+	r.ParseForm()
+	var paths []string
+	for _, entity := range r.Form["entity"] {
+		entityPath, ok := decodePath(sessionPaths(controller.paths, r), w, r, entity)
+		if !ok {
+			return
+		}
+		paths = append(paths, entityPath)
+	}
+	name := path.Base(r.FormValue("archive-name"))
+	if len(paths) == 0 || !explorer.IsArchive(name) {
+		http.Error(w, "Select entities and provide a .zip or .tar.gz archive name", 400)
+		return
+	}
+	target := strings.TrimSuffix(currentDir, "/") + "/" + name
+	userRoot := userExplorer(r, controller.explorer)
+	job := controller.jobs.Start(auth.User(r), "Pack "+name, func(progress func(int64, int64)) error {
+		return userRoot.Pack(paths, target, progress)
+	})
+	http.Redirect(w, r, "/jobs/#"+job.ID, 303)
+}
+
+// JobsHandler shows the list of background jobs of the user
+func (controller *archiveController) JobsHandler(w http.ResponseWriter, r *http.Request) {
+	userJobs := []jobs.Job{}
+	for _, job := range controller.jobs.List() {
+		if job.Owner == auth.User(r) {
+			userJobs = append(userJobs, job)
+		}
+	}
+	tpl, err := template.ParseFiles(
+		"server/templates/layout.html",
+		"server/templates/navigation.html",
+		"server/templates/content/jobs.html",
+	)
+	if err != nil {
+		panic(err)
+	}
+	tpl.Execute(w, map[string]interface{}{
+		"CurrentUser": auth.User(r),
+		"Jobs":        userJobs,
+	})
+}
+
+// JobHandler reports the progress of a single background job of the user
+// as JSON
+func (controller *archiveController) JobHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	job, ok := controller.jobs.Get(vars[current_job])
+	if !ok || job.Owner != auth.User(r) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// archiveBaseName returns the archive name without its extension
+func archiveBaseName(archive string) string {
+	name := path.Base(archive)
+	lower := strings.ToLower(name)
+	for _, extension := range []string{".tar.gz", ".tgz", ".tar", ".zip"} {
+		if strings.HasSuffix(lower, extension) {
+			return name[:len(name)-len(extension)]
+		}
+	}
+	return name
+}
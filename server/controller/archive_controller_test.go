@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/jobs"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_archiveBaseName_ShouldStripArchiveExtension(t *testing.T) {
+	assert.Equal(t, "delivery", archiveBaseName("C:/share/delivery.tar.gz"))
+	assert.Equal(t, "Delivery", archiveBaseName("C:/share/Delivery.ZIP"))
+	assert.Equal(t, "notes.txt", archiveBaseName("C:/share/notes.txt"))
+}
+
+func Test_archiveController_ShouldOnlyReportJobsOfUser(t *testing.T) {
+	manager := jobs.NewManager()
+	job := manager.Start("alice", "Pack all.zip", func(progress func(int64, int64)) error { return nil })
+	controller := NewArchiveController(nil, explorer.New("C:/share"), manager, explorer.DefaultArchiveLimits)
+	report := func(user string) int {
+		r := auth.WithIdentity(httptest.NewRequest("GET", "/jobs/"+job.ID+"/", nil), auth.Identity{Name: user})
+		w := httptest.NewRecorder()
+		controller.JobHandler(w, mux.SetURLVars(r, map[string]string{current_job: job.ID}))
+		return w.Code
+	}
+
+	assert.Equal(t, 200, report("alice"))
+	assert.Equal(t, 404, report("bob"))
+}
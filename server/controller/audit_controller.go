@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/audit"
+	"github.com/doojin/file-explorer/auth"
+	"html/template"
+	"net/http"
+)
+
+// AuditViewLimit is the number of events shown in the audit view
+const AuditViewLimit = 200
+
+type auditController struct {
+	log    *audit.Log
+	admins []string
+}
+
+// NewAuditController creates a new instance of auditController. Admins are
+// the users and groups allowed to see the audit log
+func NewAuditController(log *audit.Log, admins []string) (controller auditController) {
+	controller.log = log
+	controller.admins = admins
+	return
+}
+
+// AuditHandler shows the most recent audit events selected by the filter
+// in the query
+func (controller *auditController) AuditHandler(w http.ResponseWriter, r *http.Request) {
+	if !controller.admin(r) {
+		http.Error(w, "Only admins can see the audit log", 403)
+		return
+	}
+	filter := audit.Filter{
+		User:   r.FormValue("user"),
+		Action: r.FormValue("action"),
+		Path:   r.FormValue("path"),
+		Result: r.FormValue("result"),
+	}
+	events, err := controller.log.Recent(filter, AuditViewLimit)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	tpl, err := template.ParseFiles(
+		"server/templates/layout.html",
+		"server/templates/navigation.html",
+		"server/templates/content/audit.html",
+	)
+	if err != nil {
+		panic(err)
+	}
+	tpl.Execute(w, map[string]interface{}{
+		"CurrentUser": auth.User(r),
+		"Filter":      filter,
+		"Events":      events,
+		"Limit":       AuditViewLimit,
+		"Results": []string{
+			audit.ResultOK,
+			audit.ResultDenied,
+			audit.ResultNotFound,
+			audit.ResultFailed,
+		},
+	})
+}
+
+// admin tells whether the user or one of the groups is an admin. Without
+// authentication everybody can see the audit log
+func (controller *auditController) admin(r *http.Request) bool {
+	identity := auth.IdentityOf(r)
+	if identity.Name == "" {
+		return true
+	}
+	for _, admin := range controller.admins {
+		if admin == identity.Name {
+			return true
+		}
+		for _, group := range identity.Groups {
+			if admin == group {
+				return true
+			}
+		}
+	}
+	return false
+}
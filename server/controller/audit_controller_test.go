@@ -0,0 +1,20 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/auth"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_auditController_ShouldOnlyAllowAdmins(t *testing.T) {
+	controller := NewAuditController(nil, []string{"alice", "admins"})
+	request := func(identity auth.Identity) bool {
+		return controller.admin(auth.WithIdentity(httptest.NewRequest("GET", "/audit/", nil), identity))
+	}
+
+	assert.True(t, request(auth.Identity{Name: "alice"}))
+	assert.True(t, request(auth.Identity{Name: "bob", Groups: []string{"staff", "admins"}}))
+	assert.False(t, request(auth.Identity{Name: "eve", Groups: []string{"staff"}}))
+	assert.True(t, request(auth.Identity{}))
+}
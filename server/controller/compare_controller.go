@@ -0,0 +1,127 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/diff"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/doojin/file-explorer/preview"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// MaxCompareSize is the maximum size of compared files
+const MaxCompareSize = 1024 * 1024
+
+var errTooLarge = errors.New("File is too large to compare")
+
+type compareController struct {
+	paths    pathref.Strategy
+	explorer explorer.Explorer
+}
+
+// NewCompareController creates a new instance of compareController
+func NewCompareController(paths pathref.Strategy, explorer explorer.Explorer) (controller compareController) {
+	controller.paths = paths
+	controller.explorer = explorer
+	return
+}
+
+// CompareHandler shows the line diff of two selected files
+func (controller *compareController) CompareHandler(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	tokens := r.Form["entity"]
+	if len(tokens) != 2 {
+		http.Error(w, "Select exactly two files to compare", 400)
+		return
+	}
+	tpl, err := template.ParseFiles(
+		"server/templates/layout.html",
+		"server/templates/navigation.html",
+		"server/templates/content/compare.html",
+	)
+	if err != nil {
+		panic(err)
+	}
+	var files [2]explorer.File
+	var contents [2][]byte
+	tooLarge := false
+	for i, token := range tokens {
+		filePath, ok := decodePath(sessionPaths(controller.paths, r), w, r, token)
+		if !ok {
+			return
+		}
+		files[i], contents[i], err = controller.read(r, filePath)
+		if err == errTooLarge {
+			tooLarge = true
+			continue
+		}
+		if err == explorer.ERR_OUT_OF_ROOT {
+			http.Redirect(w, r, "/", 302)
+			return
+		}
+		if err == explorer.ERR_ACCESS_DENIED {
+			http.Error(w, err.Error(), 403)
+			return
+		}
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	}
+	query := url.Values{"entity": tokens}
+	data := map[string]interface{}{
+		"CurrentUser": auth.User(r),
+		"Old":         files[0],
+		"New":         files[1],
+		"Query":       template.URL(query.Encode()),
+		"SideBySide":  r.FormValue("mode") == "side",
+	}
+	switch {
+	case tooLarge:
+		data["TooLarge"] = true
+	case isBinary(files[0].Name, contents[0]) || isBinary(files[1].Name, contents[1]):
+		data["Binary"] = true
+		data["Identical"] = bytes.Equal(contents[0], contents[1])
+	default:
+		lines := diff.Lines(
+			diff.SplitLines(string(contents[0])),
+			diff.SplitLines(string(contents[1])),
+		)
+		data["Identical"] = !diff.Changed(lines)
+		data["Unified"] = diff.Unified(lines)
+		data["Rows"] = diff.SideBySide(lines)
+	}
+	tpl.Execute(w, data)
+}
+
+// read returns the file content unless the file exceeds MaxCompareSize
+func (controller *compareController) read(r *http.Request, filePath string) (file explorer.File, content []byte, err error) {
+	reader, file, err := userExplorer(r, controller.explorer).Open(filePath)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+	content, err = ioutil.ReadAll(io.LimitReader(reader, MaxCompareSize+1))
+	if len(content) > MaxCompareSize {
+		return file, nil, errTooLarge
+	}
+	return
+}
+
+func isBinary(name string, content []byte) bool {
+	head := content
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	kind := preview.Kind(name, head)
+	if kind != preview.KindText && kind != preview.KindMarkdown {
+		return true
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
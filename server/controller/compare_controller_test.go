@@ -0,0 +1,13 @@
+package controller
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_isBinary_ShouldDetectBinaryContent(t *testing.T) {
+	assert.False(t, isBinary("config.ini", []byte("port = 8080\n")))
+	assert.False(t, isBinary("empty.txt", []byte{}))
+	assert.True(t, isBinary("data.csv", []byte{'a', 0, 'b'}))
+	assert.True(t, isBinary("photo.png", []byte("text")))
+}
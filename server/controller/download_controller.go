@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/gorilla/mux"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+const current_file = "file"
+
+type downloadController struct {
+	paths    pathref.Strategy
+	explorer explorer.Explorer
+}
+
+// NewDownloadController creates a new instance of downloadController
+func NewDownloadController(paths pathref.Strategy, explorer explorer.Explorer) (controller downloadController) {
+	controller.paths = paths
+	controller.explorer = explorer
+	return
+}
+
+// DownloadHandler serves files and archive members as attachments
+func (controller *downloadController) DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	path, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	reader, file, err := userExplorer(r, controller.explorer).Open(path)
+	if err == explorer.ERR_OUT_OF_ROOT {
+		http.Redirect(w, r, "/", 302)
+		return
+	}
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer reader.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(
+		"attachment",
+		map[string]string{"filename": file.Name},
+	))
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	io.Copy(w, reader)
+}
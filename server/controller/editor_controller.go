@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/diff"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/doojin/file-explorer/preview"
+	"github.com/gorilla/mux"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+type editorController struct {
+	paths    pathref.Strategy
+	explorer explorer.Explorer
+}
+
+// NewEditorController creates a new instance of editorController
+func NewEditorController(paths pathref.Strategy, explorer explorer.Explorer) (controller editorController) {
+	controller.paths = paths
+	controller.explorer = explorer
+	return
+}
+
+// EditHandler shows the editor for a text file
+func (controller *editorController) EditHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	filePath, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	text, version, ok := controller.load(w, r, filePath)
+	if !ok {
+		return
+	}
+	w.Header().Set("ETag", `"`+version+`"`)
+	controller.render(w, map[string]interface{}{
+		"CurrentUser": auth.User(r),
+		"Path":        filePath,
+		"Token":       vars[current_file],
+		"Content":     text,
+		"Version":     version,
+	})
+}
+
+// SaveHandler writes the edited content unless the file was changed on
+// disk after it had been loaded. Conflicts are shown as a diff between the
+// file on disk and the submitted content
+func (controller *editorController) SaveHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	filePath, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	content := r.FormValue("content")
+	version := r.FormValue("version")
+	if len(content) > preview.MaxTextSize {
+		http.Error(w, "Content is too large", 413)
+		return
+	}
+	text, currentVersion, ok := controller.load(w, r, filePath)
+	if !ok {
+		return
+	}
+	// Browsers submit CRLF line endings, files using LF keep them
+	if !strings.Contains(text, "\r\n") {
+		content = strings.Replace(content, "\r\n", "\n", -1)
+	}
+	err := userExplorer(r, controller.explorer).WriteFile(filePath, []byte(content), version)
+	if err == explorer.ERR_CONFLICT {
+		w.WriteHeader(409)
+		controller.render(w, map[string]interface{}{
+			"CurrentUser": auth.User(r),
+			"Path":        filePath,
+			"Token":       vars[current_file],
+			"Content":     content,
+			"Version":     currentVersion,
+			"Conflict":    diff.Lines(diff.SplitLines(text), diff.SplitLines(content)),
+		})
+		return
+	}
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	http.Redirect(w, r, "/preview/"+vars[current_file]+"/", 303)
+}
+
+// load reads the current text and version of the file. Only files which
+// can be shown as text completely are editable
+func (controller *editorController) load(w http.ResponseWriter, r *http.Request,
+	filePath string) (text string, version string, ok bool) {
+	userRoot := userExplorer(r, controller.explorer)
+	version, err := userRoot.Version(filePath)
+	if err == nil {
+		err = userRoot.Check(filePath, explorer.Write)
+	}
+	if err == explorer.ERR_OUT_OF_ROOT {
+		http.Redirect(w, r, "/", 302)
+		return
+	}
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	reader, file, err := userRoot.Open(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer reader.Close()
+	filePreview, err := preview.Read(file.Name, reader)
+	if err != nil || filePreview.Truncated ||
+		(filePreview.Kind != preview.KindText && filePreview.Kind != preview.KindMarkdown) {
+		http.Error(w, "Only text files can be edited", 415)
+		return
+	}
+	return filePreview.Text, version, true
+}
+
+func (controller *editorController) render(w http.ResponseWriter, data map[string]interface{}) {
+	tpl, err := template.ParseFiles(
+		"server/templates/layout.html",
+		"server/templates/navigation.html",
+		"server/templates/content/edit.html",
+	)
+	if err != nil {
+		panic(err)
+	}
+	tpl.Execute(w, data)
+}
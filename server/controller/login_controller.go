@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/audit"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/session"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+type loginController struct {
+	authenticator *auth.Authenticator
+}
+
+// NewLoginController creates a new instance of loginController
+func NewLoginController(authenticator *auth.Authenticator) (controller loginController) {
+	controller.authenticator = authenticator
+	return
+}
+
+// LoginFormHandler shows the login page
+func (controller *loginController) LoginFormHandler(w http.ResponseWriter, r *http.Request) {
+	controller.render(w, map[string]interface{}{
+		"Next": r.URL.Query().Get("next"),
+	})
+}
+
+// LoginHandler starts a session and goes back to the page which required
+// the login
+func (controller *loginController) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	user := r.FormValue("user")
+	audit.SetUser(r, user)
+	id, err := controller.authenticator.Login(user, r.FormValue("password"), auth.ClientAddress(r))
+	if err == auth.ERR_INVALID_CREDENTIALS || err == auth.ERR_LOCKED_OUT {
+		w.WriteHeader(401)
+		controller.render(w, map[string]interface{}{
+			"Next":  r.FormValue("next"),
+			"User":  user,
+			"Error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     auth.CookieName,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	// References bound to the anonymous session must not survive the login
+	session.Expire(w)
+	http.Redirect(w, r, localRedirect(r.FormValue("next")), 302)
+}
+
+// LogoutHandler ends the session
+func (controller *loginController) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(auth.CookieName); err == nil {
+		controller.authenticator.Sessions.Delete(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: auth.CookieName, Path: "/", MaxAge: -1})
+	session.Expire(w)
+	http.Redirect(w, r, auth.LoginPath, 302)
+}
+
+func (controller *loginController) render(w http.ResponseWriter, data map[string]interface{}) {
+	tpl, err := template.ParseFiles(
+		"server/templates/layout.html",
+		"server/templates/public_navigation.html",
+		"server/templates/content/login.html",
+	)
+	if err != nil {
+		panic(err)
+	}
+	tpl.Execute(w, data)
+}
+
+// localRedirect returns the target if it is a path on this server, so
+// that the login page can't redirect to other sites
+func localRedirect(target string) string {
+	if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	return target
+}
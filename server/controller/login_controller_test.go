@@ -0,0 +1,14 @@
+package controller
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_localRedirect_ShouldStayOnServer(t *testing.T) {
+	assert.Equal(t, "/scan/token/?sort=name", localRedirect("/scan/token/?sort=name"))
+	assert.Equal(t, "/", localRedirect(""))
+	assert.Equal(t, "/", localRedirect("https://example.com/"))
+	assert.Equal(t, "/", localRedirect("//example.com/"))
+	assert.Equal(t, "/", localRedirect("/\\example.com/"))
+}
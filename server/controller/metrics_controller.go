@@ -0,0 +1,35 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/jobs"
+	"github.com/doojin/file-explorer/metrics"
+	"github.com/doojin/file-explorer/thumbnail"
+	"net/http"
+)
+
+type metricsController struct {
+	jobs        *jobs.Manager
+	thumbnailer *thumbnail.Thumbnailer
+}
+
+// NewMetricsController creates a new instance of metricsController
+func NewMetricsController(jobs *jobs.Manager, thumbnailer *thumbnail.Thumbnailer) (controller metricsController) {
+	controller.jobs = jobs
+	controller.thumbnailer = thumbnailer
+	return
+}
+
+// MetricsHandler serves the metrics in the Prometheus text format
+func (controller *metricsController) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metrics.Default.Write(w); err != nil {
+		return
+	}
+	hits, misses := controller.thumbnailer.Stats()
+	metrics.WriteValue(w, "gauge", "file_explorer_jobs_active",
+		"Background jobs which are running", float64(controller.jobs.Active()))
+	metrics.WriteValue(w, "counter", "file_explorer_thumbnail_cache_hits_total",
+		"Thumbnails served from the cache", float64(hits))
+	metrics.WriteValue(w, "counter", "file_explorer_thumbnail_cache_misses_total",
+		"Thumbnails which were not in the cache", float64(misses))
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/doojin/file-explorer/preview"
+	"github.com/gorilla/mux"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+)
+
+type previewController struct {
+	paths    pathref.Strategy
+	explorer explorer.Explorer
+}
+
+// NewPreviewController creates a new instance of previewController
+func NewPreviewController(paths pathref.Strategy, explorer explorer.Explorer) (controller previewController) {
+	controller.paths = paths
+	controller.explorer = explorer
+	return
+}
+
+// PreviewHandler shows the file content inside the explorer layout
+func (controller *previewController) PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	tpl, err := template.ParseFiles(
+		"server/templates/layout.html",
+		"server/templates/navigation.html",
+		"server/templates/content/preview.html",
+	)
+	if err != nil {
+		panic(err)
+	}
+	filePath, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	reader, file, err := userExplorer(r, controller.explorer).Open(filePath)
+	if err == explorer.ERR_OUT_OF_ROOT {
+		http.Redirect(w, r, "/", 302)
+		return
+	}
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer reader.Close()
+	filePreview, err := preview.Read(file.Name, reader)
+	if err != nil {
+		http.Error(w, explorer.ERR_CANNOT_READ.Error(), 500)
+		return
+	}
+	parentDir, _ := sessionPaths(controller.paths, r).Encode(getParentDir(filePath))
+	// Archive members have no version and can't be edited
+	_, versionErr := userExplorer(r, controller.explorer).Version(filePath)
+	writable := userExplorer(r, controller.explorer).Allows(filePath, explorer.Write)
+	isText := filePreview.Kind == preview.KindText || filePreview.Kind == preview.KindMarkdown
+	data := map[string]interface{}{
+		"CurrentUser": auth.User(r),
+		"File":        file,
+		"Token":       vars[current_file],
+		"Parent":      parentDir,
+		"Preview":     filePreview,
+		"Editable":    isText && !filePreview.Truncated && versionErr == nil && writable,
+	}
+	if filePreview.Kind == preview.KindMarkdown {
+		data["Markdown"] = preview.Markdown(filePreview.Text)
+	}
+	tpl.Execute(w, data)
+}
+
+// RawHandler serves the file content inline, so that the browser can
+// display images, PDF, audio and video with its own viewers
+func (controller *previewController) RawHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	filePath, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	reader, file, err := userExplorer(r, controller.explorer).Open(filePath)
+	if err == explorer.ERR_OUT_OF_ROOT {
+		http.Redirect(w, r, "/", 302)
+		return
+	}
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer reader.Close()
+	contentType := mime.TypeByExtension(path.Ext(file.Name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Files are served from the explorer origin, scripts inside them
+	// (HTML, SVG) must not run with its privileges. Browsers refuse to
+	// show PDF in a sandbox, and PDF viewers don't run page scripts
+	if contentType != "application/pdf" {
+		w.Header().Set("Content-Security-Policy", "sandbox")
+	}
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, file.Name, file.ModTime, seeker)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	io.Copy(w, reader)
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"encoding/base64"
+	"github.com/doojin/file-explorer/crypto"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newRoutingServer serves the decoded path of /scan/{dir}/ references
+func newRoutingServer(paths pathref.Strategy) *httptest.Server {
+	router := mux.NewRouter()
+	router.UseEncodedPath()
+	router.HandleFunc("/scan/{dir}/", func(w http.ResponseWriter, r *http.Request) {
+		path, ok := decodePath(paths, w, r, mux.Vars(r)[current_dir])
+		if ok {
+			w.Write([]byte(path))
+		}
+	})
+	return httptest.NewServer(router)
+}
+
+func get(t *testing.T, url string) (status int, body string) {
+	response, err := http.Get(url)
+	assert.Equal(t, nil, err)
+	defer response.Body.Close()
+	content, _ := ioutil.ReadAll(response.Body)
+	return response.StatusCode, string(content)
+}
+
+func Test_Routing_ShouldRoundTripTokensThroughURLPaths(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	server := newRoutingServer(pathref.NewEncrypted(encoder))
+	defer server.Close()
+
+	for i := 0; i < 200; i++ {
+		path := "C:/share/" + strings.Repeat("dir ?#%+/", i%7) + strings.Repeat("x", i)
+		token, _ := encoder.Encrypt(path)
+
+		status, body := get(t, server.URL+"/scan/"+token+"/")
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, path, body)
+	}
+}
+
+func Test_Routing_ShouldAcceptLegacyTokens(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	server := newRoutingServer(pathref.NewEncrypted(encoder))
+	defer server.Close()
+
+	for i := 0; i < 50; i++ {
+		path := "C:/share/" + strings.Repeat("x", i)
+		token, _ := encoder.Encrypt(path)
+		text, _ := base64.RawURLEncoding.DecodeString(token)
+		legacy := strings.Replace(base64.StdEncoding.EncodeToString(text), "/", "$", -1)
+
+		status, body := get(t, server.URL+"/scan/"+legacy+"/")
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, path, body)
+	}
+}
+
+func Test_Routing_ShouldRejectModifiedTokens(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	server := newRoutingServer(pathref.NewEncrypted(encoder))
+	defer server.Close()
+	token, _ := encoder.Encrypt("C:/share")
+	// The last character can carry unused bits, so a middle one is changed
+	middle := len(token) / 2
+	modified := token[:middle] + "A" + token[middle+1:]
+	if modified == token {
+		modified = token[:middle] + "B" + token[middle+1:]
+	}
+
+	status, _ := get(t, server.URL+"/scan/"+modified+"/")
+
+	assert.Equal(t, 403, status)
+}
+
+func Test_Routing_ShouldRoundTripSignedReferencesThroughURLPaths(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	paths := pathref.NewSigned("C:/share", encoder)
+	server := newRoutingServer(paths)
+	defer server.Close()
+
+	for _, path := range []string{"C:/share", "C:/share/docs/img", "C:/share/a b/c?d#e%f+g.txt"} {
+		ref, _ := paths.Encode(path)
+
+		status, body := get(t, server.URL+"/scan/"+ref+"/")
+
+		assert.Equal(t, 200, status)
+		assert.Equal(t, path, body)
+	}
+}
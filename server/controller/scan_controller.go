@@ -3,25 +3,42 @@
 package controller
 
 import (
+	"github.com/doojin/file-explorer/audit"
+	"github.com/doojin/file-explorer/auth"
 	"github.com/doojin/file-explorer/crypto"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/doojin/file-explorer/session"
+	"github.com/op/go-logging"
 	"net/http"
 	"html/template"
 	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/logs"
+	"github.com/doojin/file-explorer/metrics"
 	"github.com/gorilla/mux"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const current_dir = "dir"
 
+var logger = logging.MustGetLogger("Controller")
+
+var (
+	searchDuration = metrics.NewHistogram("file_explorer_search_duration_seconds",
+		"Duration of searches", metrics.DefaultBuckets)
+	searchResults = metrics.NewHistogram("file_explorer_search_results",
+		"Files and directories found by searches", []float64{0, 1, 10, 100, 1000, 10000})
+)
+
 type scanController struct {
-	encoder  crypto.Encoder
+	paths    pathref.Strategy
 	explorer explorer.Explorer
 }
 
 // NewScanController creates a new instance of scanController
-func NewScanController(encoder crypto.Encoder, explorer explorer.Explorer) (controller scanController) {
-	controller.encoder = encoder
+func NewScanController(paths pathref.Strategy, explorer explorer.Explorer) (controller scanController) {
+	controller.paths = paths
 	controller.explorer = explorer
 	return
 }
@@ -36,17 +53,24 @@ func (controller *scanController) HomeHandler(w http.ResponseWriter, r *http.Req
 	if err != nil {
 		panic(err)
 	}
-	directories, _ := controller.explorer.RootDirectories()
-	files, _ := controller.explorer.RootFiles()
-	files, directories = controller.encodeEntities(files, directories)
-	parentDir, _ := controller.encoder.Encrypt(
-		getParentDir(controller.explorer.Root),
+	userRoot := userExplorer(r, controller.explorer)
+	audit.AddPath(r, userRoot.Root)
+	directories, _ := userRoot.RootDirectories()
+	files, _ := userRoot.RootFiles()
+	files, directories = controller.encodeEntities(r, files, directories)
+	parentDir, _ := sessionPaths(controller.paths, r).Encode(
+		getParentDir(userRoot.Root),
 	)
+	current, _ := sessionPaths(controller.paths, r).Encode(userRoot.Root)
 	tpl.Execute(w, map[string]interface{}{
+		"CurrentUser": auth.User(r),
 		"Directories": directories,
 		"Files": files,
-		"Path": controller.explorer.Root,
+		"Path": userRoot.Root,
+		"Writable": userRoot.Allows(userRoot.Root, explorer.Write),
 		"Parent": parentDir,
+		"Current": current,
+		"Grid": r.FormValue("view") == "grid",
 	})
 }
 
@@ -61,23 +85,34 @@ func (controller *scanController) ScanHandler(w http.ResponseWriter, r *http.Req
 	if err != nil {
 		panic(err)
 	}
-	currentDir, _ := controller.encoder.Decrypt(vars[current_dir])
-	directories, err := controller.explorer.Directories(currentDir)
+	currentDir, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_dir])
+	if !ok {
+		return
+	}
+	directories, err := userExplorer(r, controller.explorer).Directories(currentDir)
 	// Nice try tho..
 	if err == explorer.ERR_OUT_OF_ROOT {
 		http.Redirect(w, r, "/", 302)
 		return
 	}
-	files, _ := controller.explorer.Files(currentDir)
-	files, directories = controller.encodeEntities(files, directories)
-	parentDir, _ := controller.encoder.Encrypt(
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	files, _ := userExplorer(r, controller.explorer).Files(currentDir)
+	files, directories = controller.encodeEntities(r, files, directories)
+	parentDir, _ := sessionPaths(controller.paths, r).Encode(
 		getParentDir(currentDir),
 	)
 	tpl.Execute(w, map[string]interface{}{
+		"CurrentUser": auth.User(r),
 		"Directories": directories,
 		"Files": files,
 		"Path": currentDir,
+		"Writable": userExplorer(r, controller.explorer).Allows(currentDir, explorer.Write),
 		"Parent": parentDir,
+		"Current": vars[current_dir],
+		"Grid": r.FormValue("view") == "grid",
 	})
 }
 
@@ -94,12 +129,16 @@ func (controller *scanController) SearchHandler(w http.ResponseWriter, r *http.R
 	if err != nil {
 		panic(err)
 	}
-	files, directories := controller.explorer.FindEntities(
-		controller.explorer.Root,
+	userRoot := userExplorer(r, controller.explorer)
+	audit.AddPath(r, userRoot.Root)
+	started := time.Now()
+	files, directories := userRoot.FindEntities(
+		userRoot.Root,
 		entityName,
 		0,
 		0,
 	)
+	searchDuration.Observe(time.Since(started).Seconds())
 	// Removing files from search
 	if fileFlag != "yes" {
 		files = []explorer.File{}
@@ -108,20 +147,23 @@ func (controller *scanController) SearchHandler(w http.ResponseWriter, r *http.R
 	if dirFlag != "yes" {
 		directories = []explorer.Directory{}
 	}
+	searchResults.Observe(float64(len(files) + len(directories)))
 	tpl.Execute(w, map[string]interface{}{
+		"CurrentUser": auth.User(r),
 		"Files": files,
 		"Directories": directories,
 	})
 }
 
-func (controller *scanController) encodeEntities(files []explorer.File,
+func (controller *scanController) encodeEntities(r *http.Request, files []explorer.File,
 directories []explorer.Directory) ([]explorer.File, []explorer.Directory) {
+	paths := sessionPaths(controller.paths, r)
 	for key, file := range files {
-		files[key].Path, _ = controller.encoder.Encrypt(file.Path)
+		files[key].Path, _ = paths.Encode(file.Path)
 	}
 
 	for key, directory := range directories {
-		directories[key].Path, _ = controller.encoder.Encrypt(directory.Path)
+		directories[key].Path, _ = paths.Encode(directory.Path)
 	}
 	return files, directories
 }
@@ -129,4 +171,53 @@ directories []explorer.Directory) ([]explorer.File, []explorer.Directory) {
 func getParentDir(path string) string {
 	parentDir := strings.Replace(filepath.Dir(path), "\\", "/", -1)
 	return parentDir
-}
\ No newline at end of file
+}
+
+// sessionPaths binds the path references to the session of the request,
+// if the request has a session and the strategy supports binding. With
+// authentication references are bound to the logged in user instead, so
+// that they can't be used by other users. Decoded paths are recorded in
+// the audit log
+func sessionPaths(paths pathref.Strategy, r *http.Request) pathref.Strategy {
+	binder, ok := paths.(pathref.Binder)
+	if id := session.ID(r); ok && id != "" {
+		if user := auth.User(r); user != "" {
+			id = "user " + user
+		}
+		paths = binder.Bind(id)
+	}
+	return auditedPaths{paths, r}
+}
+
+// auditedPaths records the paths decoded for the request
+type auditedPaths struct {
+	pathref.Strategy
+	r *http.Request
+}
+
+func (paths auditedPaths) Decode(ref string) (path string, err error) {
+	path, err = paths.Strategy.Decode(ref)
+	if err == nil {
+		audit.AddPath(paths.r, path)
+	}
+	return
+}
+
+// decodePath decodes a path reference received from the client. Modified
+// references are answered with 403, unknown ones with 404 and malformed
+// ones with 400
+func decodePath(paths pathref.Strategy, w http.ResponseWriter, r *http.Request, ref string) (path string, ok bool) {
+	path, err := paths.Decode(ref)
+	switch err {
+	case nil:
+		return path, true
+	case crypto.ERR_INVALID_TOKEN, crypto.ERR_INVALID_SIGNATURE, crypto.ERR_UNKNOWN_KEY, crypto.ERR_RETIRED_KEY:
+		logs.Request(r, logger).Warningf("Rejected modified path reference %q", ref)
+		http.Error(w, err.Error(), 403)
+	case pathref.ERR_UNKNOWN_REFERENCE:
+		http.Error(w, err.Error(), 404)
+	default:
+		http.Error(w, err.Error(), 400)
+	}
+	return "", false
+}
@@ -1,16 +1,21 @@
 package controller
 
 import (
+	"github.com/doojin/file-explorer/auth"
 	"testing"
 	"github.com/doojin/file-explorer/explorer"
 	"github.com/doojin/file-explorer/crypto"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/doojin/file-explorer/session"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 )
 
 func Test_encodeEntities_ShouldEncodeEntitiesCorrectly(t *testing.T) {
 	exp := explorer.New("dummy root")
 	encoder, _ := crypto.NewEncoder("1234567890123456")
-	controller := NewScanController(encoder, exp)
+	controller := NewScanController(pathref.NewEncrypted(encoder), exp)
 	files := []explorer.File{
 		explorer.File{
 			Path: "file path",
@@ -22,7 +27,7 @@ func Test_encodeEntities_ShouldEncodeEntitiesCorrectly(t *testing.T) {
 		},
 	}
 
-	files, directories = controller.encodeEntities(files, directories)
+	files, directories = controller.encodeEntities(httptest.NewRequest("GET", "/", nil), files, directories)
 
 	encodedFilePath := files[0].Path
 	encodedDirectoryPath := directories[0].Path
@@ -40,4 +45,100 @@ func Test_getParentDir_ShouldReturnParentDirCorrectly(t *testing.T) {
 	parentDir := getParentDir(currentDir)
 
 	assert.Equal(t, "C:/MyDir", parentDir)
-}
\ No newline at end of file
+}
+
+func Test_decodePath_ShouldDecryptValidTokens(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	token, _ := encoder.Encrypt("C:/MyDir")
+	w := httptest.NewRecorder()
+
+	path, ok := decodePath(pathref.NewEncrypted(encoder), w, httptest.NewRequest("GET", "/", nil), token)
+
+	assert.True(t, ok)
+	assert.Equal(t, "C:/MyDir", path)
+	assert.Equal(t, 200, w.Code)
+}
+
+func Test_decodePath_ShouldAnswerModifiedTokensWithForbidden(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	otherEncoder, _ := crypto.NewEncoder("abcdefghijklmnop")
+	token, _ := otherEncoder.Encrypt("C:/MyDir")
+	w := httptest.NewRecorder()
+
+	_, ok := decodePath(pathref.NewEncrypted(encoder), w, httptest.NewRequest("GET", "/", nil), token)
+
+	assert.False(t, ok)
+	assert.Equal(t, 403, w.Code)
+}
+
+func Test_decodePath_ShouldAnswerMalformedTokensWithBadRequest(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	w := httptest.NewRecorder()
+
+	_, ok := decodePath(pathref.NewEncrypted(encoder), w, httptest.NewRequest("GET", "/", nil), "not a token")
+
+	assert.False(t, ok)
+	assert.Equal(t, 400, w.Code)
+}
+
+func Test_decodePath_ShouldAnswerUnknownReferencesWithNotFound(t *testing.T) {
+	registry, _ := pathref.NewRegistry("C:/share", "")
+	w := httptest.NewRecorder()
+
+	_, ok := decodePath(registry, w, httptest.NewRequest("GET", "/", nil), "unknown")
+
+	assert.False(t, ok)
+	assert.Equal(t, 404, w.Code)
+}
+
+func Test_sessionPaths_ShouldBindReferencesToSession(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	paths := pathref.NewEncrypted(encoder)
+	var refs []string
+	var decodeErrs []error
+	handler := session.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(refs) > 0 {
+			_, err := sessionPaths(paths, r).Decode(refs[0])
+			decodeErrs = append(decodeErrs, err)
+		}
+		ref, _ := sessionPaths(paths, r).Encode("C:/share")
+		refs = append(refs, ref)
+	}))
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest("GET", "/", nil))
+	sameSession := httptest.NewRequest("GET", "/", nil)
+	sameSession.AddCookie(first.Result().Cookies()[0])
+
+	handler.ServeHTTP(httptest.NewRecorder(), sameSession)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	_, unboundErr := paths.Decode(refs[0])
+	assert.Equal(t, nil, decodeErrs[0])
+	assert.Equal(t, crypto.ERR_INVALID_TOKEN, decodeErrs[1])
+	assert.Equal(t, crypto.ERR_INVALID_TOKEN, unboundErr)
+}
+
+func Test_sessionPaths_ShouldBindReferencesToUser(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	paths := pathref.NewEncrypted(encoder)
+	var refs []string
+	var decodeErrs []error
+	handler := session.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(refs) > 0 {
+			_, err := sessionPaths(paths, r).Decode(refs[0])
+			decodeErrs = append(decodeErrs, err)
+		}
+		ref, _ := sessionPaths(paths, r).Encode("C:/share")
+		refs = append(refs, ref)
+	}))
+	request := func(user string) *http.Request {
+		return auth.WithIdentity(httptest.NewRequest("GET", "/", nil), auth.Identity{Name: user})
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), request("alice"))
+	handler.ServeHTTP(httptest.NewRecorder(), request("alice"))
+	handler.ServeHTTP(httptest.NewRecorder(), request("bob"))
+
+	assert.Equal(t, nil, decodeErrs[0])
+	assert.Equal(t, crypto.ERR_INVALID_TOKEN, decodeErrs[1])
+}
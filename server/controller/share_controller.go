@@ -0,0 +1,513 @@
+package controller
+
+import (
+	"errors"
+	"github.com/doojin/file-explorer/audit"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/crypto"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/logs"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/doojin/file-explorer/share"
+	"github.com/gorilla/mux"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits of uploads through share links: the size of a file, the size of
+// all files of a request and the number of files of a request
+const (
+	MaxShareUploadSize  = 100 * 1024 * 1024
+	MaxShareRequestSize = 250 * 1024 * 1024
+	MaxShareUploadFiles = 20
+)
+
+const (
+	current_share = "share"
+	current_token = "token"
+	expiresLayout = "2006-01-02T15:04"
+	// unlockPurpose separates signatures of unlock cookies from share
+	// tokens and path references
+	unlockPurpose = "share-unlock"
+)
+
+type shareController struct {
+	encoder  crypto.Encoder
+	paths    pathref.Strategy
+	explorer explorer.Explorer
+	store    *share.Store
+	owners   ExplorerResolver
+	lockout  *auth.Lockout
+}
+
+// NewShareController creates a new instance of shareController. Share
+// links apply the root and the rules of the user who created them, which
+// are resolved with owners. Owners is nil without access rules. Password
+// guessing locks shares out by their ID
+func NewShareController(encoder crypto.Encoder, paths pathref.Strategy, explorer explorer.Explorer,
+	store *share.Store, owners ExplorerResolver, lockout *auth.Lockout) (controller shareController) {
+	controller.encoder = encoder
+	controller.paths = paths
+	controller.explorer = explorer
+	controller.store = store
+	controller.owners = owners
+	controller.lockout = lockout
+	return
+}
+
+// CreateFormHandler shows the form for sharing a file or directory
+func (controller *shareController) CreateFormHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	filePath, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	isDir, err := userExplorer(r, controller.explorer).IsDir(filePath)
+	if err == nil {
+		err = userExplorer(r, controller.explorer).Check(filePath, explorer.Share)
+	}
+	if err == explorer.ERR_OUT_OF_ROOT {
+		http.Redirect(w, r, "/", 302)
+		return
+	}
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	controller.render(w, "share_create.html", false, map[string]interface{}{
+		"CurrentUser": auth.User(r),
+		"Path":        filePath,
+		"Token":       vars[current_file],
+		"IsDir":       isDir,
+		"Expires":     time.Now().Add(24 * time.Hour).Format(expiresLayout),
+		"Writable":    userExplorer(r, controller.explorer).Allows(filePath, explorer.Write),
+	})
+}
+
+// CreateHandler stores a new share and shows its link
+func (controller *shareController) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	filePath, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	isDir, err := userExplorer(r, controller.explorer).IsDir(filePath)
+	if err == nil && isDir {
+		// Nothing below a shared directory may be hidden from the owner
+		err = userExplorer(r, controller.explorer).CheckTree(filePath, explorer.Read|explorer.Share)
+	} else if err == nil {
+		err = userExplorer(r, controller.explorer).Check(filePath, explorer.Share)
+	}
+	if err == explorer.ERR_OUT_OF_ROOT {
+		http.Redirect(w, r, "/", 302)
+		return
+	}
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	expiresAt, err := time.ParseInLocation(expiresLayout, r.FormValue("expires"), time.Local)
+	if err != nil || !expiresAt.After(time.Now()) {
+		http.Error(w, "Expiry must be a time in the future", 400)
+		return
+	}
+	maxDownloads := 0
+	if value := r.FormValue("max-downloads"); value != "" {
+		if maxDownloads, err = strconv.Atoi(value); err != nil || maxDownloads < 0 {
+			http.Error(w, "Download limit must be a positive number", 400)
+			return
+		}
+	}
+	scope := r.FormValue("scope")
+	if scope == share.ScopeUpload && !isDir {
+		http.Error(w, "Only directories can be shared for upload", 400)
+		return
+	}
+	writable := userExplorer(r, controller.explorer).Allows(filePath, explorer.Write)
+	if scope == share.ScopeUpload && !writable {
+		http.Error(w, explorer.ERR_ACCESS_DENIED.Error(), 403)
+		return
+	}
+	created, err := controller.store.Create(auth.User(r), filePath, scope, expiresAt, r.FormValue("password"), maxDownloads)
+	if err == share.ERR_INVALID_SCOPE {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	controller.render(w, "share_create.html", false, map[string]interface{}{
+		"CurrentUser": auth.User(r),
+		"Path":        filePath,
+		"Token":       vars[current_file],
+		"IsDir":       isDir,
+		"Expires":     expiresAt.Format(expiresLayout),
+		"Writable":    writable,
+		"Link":        shareLink(r, share.Token(controller.encoder, created)),
+	})
+}
+
+// SharesHandler lists the active shares of the user
+func (controller *shareController) SharesHandler(w http.ResponseWriter, r *http.Request) {
+	type sharedEntity struct {
+		share.Share
+		Link string
+	}
+	shares := []sharedEntity{}
+	for _, active := range controller.store.Active() {
+		if !controller.owns(r, active) {
+			continue
+		}
+		shares = append(shares, sharedEntity{active, shareLink(r, share.Token(controller.encoder, active))})
+	}
+	controller.render(w, "shares.html", false, map[string]interface{}{
+		"CurrentUser": auth.User(r),
+		"Shares":      shares,
+	})
+}
+
+// RevokeHandler revokes the share, its link stops working immediately.
+// Only the owner can revoke a share
+func (controller *shareController) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)[current_share]
+	for _, active := range controller.store.Active() {
+		if active.ID != id {
+			continue
+		}
+		audit.AddPath(r, active.Path)
+		if !controller.owns(r, active) {
+			http.Error(w, "Only the owner can revoke the share", 403)
+			return
+		}
+	}
+	err := controller.store.Revoke(id)
+	if err != nil && err != share.ERR_SHARE_NOT_FOUND {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	http.Redirect(w, r, "/shares/", 303)
+}
+
+// SharedHandler serves the public page of a share link. Shared files are
+// shown with a download button, shared directories are listed for read
+// shares and show an upload form for upload shares
+func (controller *shareController) SharedHandler(w http.ResponseWriter, r *http.Request) {
+	shared, ok := controller.resolve(w, r)
+	if !ok {
+		return
+	}
+	data := map[string]interface{}{
+		"Share": shared,
+		"Token": mux.Vars(r)[current_token],
+		"Name":  path.Base(shared.Path),
+	}
+	if !controller.unlocked(r, shared) {
+		data["Locked"] = true
+		controller.render(w, "shared.html", true, data)
+		return
+	}
+	sharedExplorer, ok := controller.sharedExplorer(shared)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	isDir, err := sharedExplorer.IsDir(shared.Path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	data["IsDir"] = isDir
+	data["Uploaded"] = r.FormValue("uploaded")
+	if isDir && shared.Scope == share.ScopeRead {
+		relative, dir := sharedPath(shared, r.FormValue("path"))
+		audit.AddPath(r, dir)
+		directories, err := sharedExplorer.Directories(dir)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		files, _ := sharedExplorer.Files(dir)
+		for key, directory := range directories {
+			directories[key].Path = path.Join(relative, directory.Name)
+		}
+		for key, file := range files {
+			files[key].Path = path.Join(relative, file.Name)
+		}
+		data["Current"] = relative
+		data["Parent"] = path.Dir(relative)
+		data["Directories"] = directories
+		data["Files"] = files
+	}
+	controller.render(w, "shared.html", true, data)
+}
+
+// UnlockHandler checks the password of the share and remembers the
+// unlocked share in a signed cookie
+func (controller *shareController) UnlockHandler(w http.ResponseWriter, r *http.Request) {
+	shared, ok := controller.resolve(w, r)
+	if !ok {
+		return
+	}
+	token := mux.Vars(r)[current_token]
+	if controller.lockout.Locked(shared.ID) {
+		logs.Request(r, logger).Warningf("Share %v is locked out", shared.ID)
+		http.Error(w, auth.ERR_LOCKED_OUT.Error(), 429)
+		return
+	}
+	if !controller.store.CheckPassword(shared, r.FormValue("password")) {
+		logs.Request(r, logger).Warningf("Wrong password for share %v", shared.ID)
+		controller.lockout.Fail(shared.ID)
+		w.WriteHeader(403)
+		controller.render(w, "shared.html", true, map[string]interface{}{
+			"Share":         shared,
+			"Token":         token,
+			"Name":          path.Base(shared.Path),
+			"Locked":        true,
+			"WrongPassword": true,
+		})
+		return
+	}
+	controller.lockout.Succeed(shared.ID)
+	http.SetCookie(w, &http.Cookie{
+		Name:     "share-" + shared.ID,
+		Value:    controller.encoder.Sign(unlockPurpose, []byte(shared.ID)),
+		Path:     "/s/" + token + "/",
+		Expires:  shared.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/s/"+token+"/", 303)
+}
+
+// SharedDownloadHandler downloads the shared file or a file within the
+// shared directory. Every download counts towards the download limit
+func (controller *shareController) SharedDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	shared, ok := controller.resolve(w, r)
+	if !ok {
+		return
+	}
+	if shared.Scope != share.ScopeRead || !controller.unlocked(r, shared) {
+		http.Error(w, "Downloads are not allowed", 403)
+		return
+	}
+	_, filePath := sharedPath(shared, r.FormValue("path"))
+	audit.AddPath(r, filePath)
+	sharedExplorer, ok := controller.sharedExplorer(shared)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	reader, file, err := sharedExplorer.Open(filePath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer reader.Close()
+	if err = controller.store.CountDownload(shared.ID); err != nil {
+		http.Error(w, err.Error(), 410)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(
+		"attachment",
+		map[string]string{"filename": file.Name},
+	))
+	w.Header().Set("Content-Length", strconv.FormatInt(file.Size, 10))
+	io.Copy(w, reader)
+}
+
+// SharedUploadHandler stores files uploaded to a directory shared for
+// upload. Existing files are never overwritten
+func (controller *shareController) SharedUploadHandler(w http.ResponseWriter, r *http.Request) {
+	shared, ok := controller.resolve(w, r)
+	if !ok {
+		return
+	}
+	if shared.Scope != share.ScopeUpload || !controller.unlocked(r, shared) {
+		http.Error(w, "Uploads are not allowed", 403)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, MaxShareRequestSize)
+	r.Body = body
+	reader, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, "Select a file to upload", 400)
+		return
+	}
+	audit.AddPath(r, shared.Path)
+	sharedExplorer, ok := controller.sharedExplorer(shared)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var uploaded []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil && tooLarge(body) {
+			http.Error(w, "Request is too large", 413)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		if part.FormName() != "file" || part.FileName() == "" {
+			continue
+		}
+		if len(uploaded) == MaxShareUploadFiles {
+			http.Error(w, "Too many files, upload at most "+strconv.Itoa(MaxShareUploadFiles)+" at once", 413)
+			return
+		}
+		file, err := sharedExplorer.CreateFile(shared.Path, part.FileName(), part, MaxShareUploadSize)
+		if err != nil && tooLarge(body) {
+			err = explorer.ERR_TOO_LARGE
+		}
+		switch err {
+		case nil:
+			audit.AddPath(r, file.Path)
+			uploaded = append(uploaded, file.Name)
+			continue
+		case explorer.ERR_TOO_LARGE:
+			http.Error(w, err.Error(), 413)
+		case explorer.ERR_EXISTS:
+			http.Error(w, err.Error(), 409)
+		case explorer.ERR_INVALID_NAME:
+			http.Error(w, err.Error(), 400)
+		case explorer.ERR_ACCESS_DENIED:
+			http.Error(w, err.Error(), 403)
+		default:
+			http.Error(w, err.Error(), 500)
+		}
+		return
+	}
+	if len(uploaded) == 0 {
+		http.Error(w, "Select a file to upload", 400)
+		return
+	}
+	logs.Request(r, logger).Infof("Share %v received %v", shared.ID, strings.Join(uploaded, ", "))
+	http.Redirect(w, r, "/s/"+mux.Vars(r)[current_token]+"/?uploaded="+
+		template.URLQueryEscaper(strings.Join(uploaded, ", ")), 303)
+}
+
+// resolve returns the share of the token in the URL. Expired, revoked and
+// forged links are answered with 404
+func (controller *shareController) resolve(w http.ResponseWriter, r *http.Request) (shared share.Share, ok bool) {
+	shared, err := controller.store.Resolve(controller.encoder, mux.Vars(r)[current_token])
+	switch err {
+	case nil:
+		// Visitors of share links are recorded in the audit log by the share
+		audit.SetUser(r, "share "+shared.ID)
+		return shared, true
+	case share.ERR_SHARE_EXPIRED:
+		http.Error(w, err.Error(), 410)
+	case crypto.ERR_INVALID_SIGNATURE, share.ERR_TOKEN_MISMATCH:
+		logs.Request(r, logger).Warningf("Rejected forged share token %q", mux.Vars(r)[current_token])
+		fallthrough
+	default:
+		http.NotFound(w, r)
+	}
+	return
+}
+
+// unlocked checks if the share has no password or was unlocked with it
+func (controller *shareController) unlocked(r *http.Request, shared share.Share) bool {
+	if !shared.HasPassword() {
+		return true
+	}
+	cookie, err := r.Cookie("share-" + shared.ID)
+	if err != nil {
+		return false
+	}
+	id, err := controller.encoder.Verify(unlockPurpose, cookie.Value)
+	return err == nil && string(id) == shared.ID
+}
+
+// owns checks if the user of the request created the share. Shares
+// created before owners were recorded belong to users who can share their
+// path
+func (controller *shareController) owns(r *http.Request, shared share.Share) bool {
+	if shared.Owner == "" {
+		return userExplorer(r, controller.explorer).Allows(shared.Path, explorer.Share)
+	}
+	return shared.Owner == auth.User(r)
+}
+
+func (controller *shareController) render(w http.ResponseWriter, content string, public bool,
+	data map[string]interface{}) {
+	navigation := "server/templates/navigation.html"
+	if public {
+		navigation = "server/templates/public_navigation.html"
+	}
+	tpl, err := template.ParseFiles(
+		"server/templates/layout.html",
+		navigation,
+		"server/templates/content/"+content,
+	)
+	if err != nil {
+		panic(err)
+	}
+	tpl.Execute(w, data)
+}
+
+// sharedExplorer returns the explorer of the shared file or directory.
+// Read-only paths apply to share links as well, and so do the root and the
+// rules of the owner, as they are now. The second value is false when the
+// owner has lost access
+func (controller *shareController) sharedExplorer(shared share.Share) (sharedExplorer explorer.Explorer, ok bool) {
+	if shared.Owner == "" || controller.owners == nil {
+		return explorer.Explorer{Root: shared.Path, ReadOnly: controller.explorer.ReadOnly,
+			Scanned: controller.explorer.Scanned}, true
+	}
+	if sharedExplorer, ok = controller.owners(shared.Owner); !ok {
+		return
+	}
+	// The owner's root may be wider than the share, sharedPath keeps paths
+	// within the shared directory
+	return sharedExplorer, sharedExplorer.Allows(shared.Path, explorer.Read)
+}
+
+// tooLarge checks if reading the request body failed because the body
+// exceeded its limit
+func tooLarge(body io.Reader) bool {
+	_, err := body.Read(make([]byte, 1))
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// sharedPath resolves the path relative to the shared directory. The
+// relative path can't leave the shared directory
+func sharedPath(shared share.Share, relative string) (cleaned string, full string) {
+	cleaned = path.Clean("/" + strings.Replace(relative, "\\", "/", -1))
+	if cleaned == "/" {
+		return cleaned, shared.Path
+	}
+	return cleaned, strings.TrimSuffix(shared.Path, "/") + cleaned
+}
+
+// shareLink returns the absolute URL of the share link
+func shareLink(r *http.Request, token string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/s/" + token + "/"
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/crypto"
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/share"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_sharedPath_ShouldStayInSharedDirectory(t *testing.T) {
+	shared := share.Share{Path: "C:/share/project"}
+
+	relative, full := sharedPath(shared, "../../secret.txt")
+	rootRelative, rootFull := sharedPath(shared, "")
+	nestedRelative, nestedFull := sharedPath(shared, "docs\\..\\img/logo.png")
+
+	assert.Equal(t, "/secret.txt", relative)
+	assert.Equal(t, "C:/share/project/secret.txt", full)
+	assert.Equal(t, "/", rootRelative)
+	assert.Equal(t, "C:/share/project", rootFull)
+	assert.Equal(t, "/img/logo.png", nestedRelative)
+	assert.Equal(t, "C:/share/project/img/logo.png", nestedFull)
+}
+
+func Test_sharedExplorer_ShouldApplyRulesOfOwner(t *testing.T) {
+	owners := func(user string) (explorer.Explorer, bool) {
+		rules := explorer.Rules{{Path: "/project/secret", Deny: explorer.Read}}
+		return explorer.Explorer{Root: "C:/share", Rules: rules}, user == "alice"
+	}
+	controller := NewShareController(crypto.Encoder{}, nil, explorer.New("C:/share"), nil, owners, auth.NewLockout())
+
+	anonymous, anonymousOk := controller.sharedExplorer(share.Share{Path: "C:/share/project"})
+	alice, aliceOk := controller.sharedExplorer(share.Share{Owner: "alice", Path: "C:/share/project"})
+	_, removedOk := controller.sharedExplorer(share.Share{Owner: "bob", Path: "C:/share/project"})
+	_, deniedOk := controller.sharedExplorer(share.Share{Owner: "alice", Path: "C:/share/project/secret"})
+
+	assert.True(t, anonymousOk)
+	assert.Equal(t, "C:/share/project", anonymous.Root)
+	assert.True(t, aliceOk)
+	assert.True(t, alice.Allows("C:/share/project/a.txt", explorer.Read))
+	assert.False(t, alice.Allows("C:/share/project/secret/a.txt", explorer.Read))
+	assert.False(t, removedOk)
+	assert.False(t, deniedOk)
+}
+
+func Test_shareController_ShouldOnlyLetOwnersManageShares(t *testing.T) {
+	controller := NewShareController(crypto.Encoder{}, nil, explorer.Explorer{Root: "C:/share",
+		Rules: explorer.Rules{{Path: "/private", Deny: explorer.Share}}}, nil, nil, auth.NewLockout())
+	owns := func(user string, shared share.Share) bool {
+		return controller.owns(auth.WithIdentity(httptest.NewRequest("GET", "/shares/", nil), auth.Identity{Name: user}), shared)
+	}
+
+	assert.True(t, owns("alice", share.Share{Owner: "alice", Path: "C:/share/a.txt"}))
+	assert.False(t, owns("bob", share.Share{Owner: "alice", Path: "C:/share/a.txt"}))
+	assert.True(t, owns("bob", share.Share{Path: "C:/share/a.txt"}))
+	assert.False(t, owns("bob", share.Share{Path: "C:/share/private/a.txt"}))
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/doojin/file-explorer/thumbnail"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// thumbnailSize is the maximum width and height of thumbnails in pixels
+const thumbnailSize = 160
+
+type thumbnailController struct {
+	paths       pathref.Strategy
+	explorer    explorer.Explorer
+	thumbnailer *thumbnail.Thumbnailer
+}
+
+// NewThumbnailController creates a new instance of thumbnailController
+func NewThumbnailController(paths pathref.Strategy, explorer explorer.Explorer,
+	thumbnailer *thumbnail.Thumbnailer) (controller thumbnailController) {
+	controller.paths = paths
+	controller.explorer = explorer
+	controller.thumbnailer = thumbnailer
+	return
+}
+
+// ThumbnailHandler serves JPEG thumbnails of images
+func (controller *thumbnailController) ThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	filePath, ok := decodePath(sessionPaths(controller.paths, r), w, r, vars[current_file])
+	if !ok {
+		return
+	}
+	userRoot := userExplorer(r, controller.explorer)
+	reader, file, err := userRoot.Open(filePath)
+	if err == explorer.ERR_OUT_OF_ROOT {
+		http.Redirect(w, r, "/", 302)
+		return
+	}
+	if err == explorer.ERR_ACCESS_DENIED {
+		http.Error(w, err.Error(), 403)
+		return
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	reader.Close()
+	content, err := controller.thumbnailer.Thumbnail(file.Path, file.Size, file.ModTime, thumbnailSize,
+		func() (io.ReadCloser, error) {
+			reader, _, err := userRoot.Open(filePath)
+			return reader, err
+		})
+	if err != nil {
+		http.Error(w, err.Error(), 415)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.Write(content)
+}
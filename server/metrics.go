@@ -0,0 +1,40 @@
+package server
+
+import (
+	"github.com/doojin/file-explorer/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	requestsTotal = metrics.NewCounter("file_explorer_http_requests_total",
+		"HTTP requests by route, method and status", "route", "method", "status")
+	requestDuration = metrics.NewHistogram("file_explorer_http_request_duration_seconds",
+		"Duration of HTTP requests by route", metrics.DefaultBuckets, "route")
+	downloadedBytes = metrics.NewCounter("file_explorer_downloaded_bytes_total",
+		"Bytes of downloaded files")
+	uploadedBytes = metrics.NewCounter("file_explorer_uploaded_bytes_total",
+		"Bytes of uploaded and edited files")
+	directoriesScanned = metrics.NewCounter("file_explorer_directories_scanned_total",
+		"Directories read from disk by listings and searches")
+)
+
+// countScan counts a directory read by an explorer
+func countScan() {
+	directoriesScanned.Inc()
+}
+
+// observeRequest updates the request metrics. Requests which match no
+// route are counted with an empty route, so that scans for random URLs
+// don't create new series
+func observeRequest(r *http.Request, route string, recorder *responseRecorder, received int64, duration time.Duration) {
+	requestsTotal.Inc(route, r.Method, strconv.Itoa(recorder.status))
+	requestDuration.Observe(duration.Seconds(), route)
+	switch action := routeAction(r, route); {
+	case action == "download" && recorder.status < 400:
+		downloadedBytes.Add(float64(recorder.bytes))
+	case uploadActions[action] && recorder.status < 400:
+		uploadedBytes.Add(float64(received))
+	}
+}
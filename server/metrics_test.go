@@ -0,0 +1,46 @@
+package server
+
+import (
+	"github.com/doojin/file-explorer/auth"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_requestLogMiddleware_ShouldUpdateMetrics(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/download/{file}/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content"))
+	})
+	handler := requestLogMiddleware(router, router)
+	requests := requestsTotal.Value("/download/{file}/", "GET", "200")
+	unmatched := requestsTotal.Value("", "GET", "404")
+	downloaded := downloadedBytes.Value()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/download/token/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/random/", nil))
+
+	assert.Equal(t, requests+1, requestsTotal.Value("/download/{file}/", "GET", "200"))
+	assert.Equal(t, unmatched+1, requestsTotal.Value("", "GET", "404"))
+	assert.Equal(t, downloaded+7, downloadedBytes.Value())
+}
+
+func Test_explorers_ShouldCountScannedDirectories(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "scanned")
+	defer os.RemoveAll(dir)
+	server := testAccessServer()
+	server.Config.RootDir = dir
+	policy, _ := server.accessPolicy()
+	userExplorer, _ := policy.explorer(auth.Identity{Name: "admin", Groups: []string{"admins"}})
+	rootExplorer := server.rootExplorer()
+	scanned := directoriesScanned.Value()
+
+	rootExplorer.RootDirectories()
+	userExplorer.RootDirectories()
+
+	assert.Equal(t, scanned+2, directoriesScanned.Value())
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// configPollInterval is how often the configuration file is checked for
+// changes
+const configPollInterval = 2 * time.Second
+
+// restartSettings can't be changed while the server is running, because
+// the listener, the HTTP server, the share store, the login sessions or the
+// audit log are built from them.
+// Changes are reported and ignored until restart
+var restartSettings = []string{"Port", "TLS", "ReadTimeout", "WriteTimeout", "IdleTimeout", "SharesFile",
+	"SessionIdleTimeout", "SessionMaxAge", "AuditFile", "AuditMaxSize", "AuditBackups"}
+
+// swapHandler passes requests to the current handler. Reloading swaps the
+// handler atomically, requests in flight finish with the old one
+type swapHandler struct {
+	current atomic.Value
+}
+
+func newSwapHandler(handler http.Handler) *swapHandler {
+	swap := new(swapHandler)
+	swap.current.Store(&handler)
+	return swap
+}
+
+func (swap *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*swap.current.Load().(*http.Handler)).ServeHTTP(w, r)
+}
+
+func (swap *swapHandler) swap(handler http.Handler) {
+	swap.current.Store(&handler)
+}
+
+// reload reads the configuration again from the same sources, validates it
+// and swaps in a new handler. On errors the running configuration is kept.
+// It returns the names of changed settings which need a restart
+func (server *Server) reload() (ignored []string, err error) {
+	fresh := &Server{jobs: server.jobs, shares: server.shares, sessions: server.sessions, lockout: server.lockout,
+		audit: server.audit}
+	if err = fresh.LoadConfig(server.args, server.environ); err != nil {
+		return
+	}
+	running := reflect.ValueOf(&server.Config).Elem()
+	loaded := reflect.ValueOf(&fresh.Config).Elem()
+	for _, name := range restartSettings {
+		if !reflect.DeepEqual(running.FieldByName(name).Interface(), loaded.FieldByName(name).Interface()) {
+			field, _ := running.Type().FieldByName(name)
+			ignored = append(ignored, field.Tag.Get("json"))
+			loaded.FieldByName(name).Set(running.FieldByName(name))
+		}
+	}
+	if err = fresh.Validate(); err != nil {
+		return nil, err
+	}
+	handler, err := fresh.newHandler()
+	if err != nil {
+		return nil, err
+	}
+	server.Config = fresh.Config
+	server.configureLogging()
+	server.sessions, server.lockout = fresh.sessions, fresh.lockout
+	server.handler.swap(handler)
+	return
+}
+
+// reloadAndReport reloads the configuration and logs the outcome
+func (server *Server) reloadAndReport() {
+	if server.handler == nil {
+		return
+	}
+	ignored, err := server.reload()
+	if err != nil {
+		logger.Errorf("Configuration was not reloaded: %v", err)
+		return
+	}
+	for _, name := range ignored {
+		logger.Warningf("%v has changed, restart the server to apply it", name)
+	}
+	logger.Info("Configuration was reloaded")
+}
+
+// configChanged tells whether the configuration file was modified since
+// the last check. A broken file is reported once, not on every check
+func (server *Server) configChanged() bool {
+	if server.configFile == "" {
+		return false
+	}
+	info, err := os.Stat(server.configFile)
+	if err != nil || info.ModTime().Equal(server.configModTime) {
+		return false
+	}
+	server.configModTime = info.ModTime()
+	return true
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func reloadConfig(port int, root string, dir string) string {
+	return fmt.Sprintf("{\"port\":%v,\"root\":%q,\"key\":\"k3J9xQ2mP7vR4tW8\",\"sharesFile\":%q,\"thumbnailDir\":%q}",
+		port, root, filepath.Join(dir, "shares.json"), filepath.Join(dir, "thumbnails"))
+}
+
+func Test_reload_ShouldSwapConfigurationAndReportRestartSettings(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "reload")
+	defer os.RemoveAll(dir)
+	createConfigFile("config.json", reloadConfig(1234, dir, dir))
+	defer deleteConfigFile("config.json")
+	server := Server{}
+	server.LoadConfig([]string{"-config", "config.json"}, nil)
+	handler, err := server.newHandler()
+	assert.Equal(t, nil, err)
+	server.handler = newSwapHandler(handler)
+	root := filepath.Join(dir, "root")
+	os.Mkdir(root, 0755)
+
+	createConfigFile("config.json", reloadConfig(4321, root, dir))
+	ignored, err := server.reload()
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"port"}, ignored)
+	assert.Equal(t, 1234, server.Config.Port)
+	assert.Equal(t, root, server.Config.RootDir)
+
+	createConfigFile("config.json", reloadConfig(1234, filepath.Join(dir, "missing"), dir))
+	_, err = server.reload()
+
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, root, server.Config.RootDir)
+}
+
+func Test_configChanged_ShouldReportEveryChangeOnce(t *testing.T) {
+	createConfigFile("config.json", "{}")
+	defer deleteConfigFile("config.json")
+	server := Server{}
+	server.LoadConfig([]string{"-config", "config.json"}, nil)
+
+	unchanged := server.configChanged()
+	modTime := time.Now().Add(time.Minute)
+	os.Chtimes("config.json", modTime, modTime)
+	changed := server.configChanged()
+	checkedAgain := server.configChanged()
+
+	assert.False(t, unchanged)
+	assert.True(t, changed)
+	assert.False(t, checkedAgain)
+	assert.False(t, new(Server).configChanged())
+}
+
+func Test_swapHandler_ShouldServeWithCurrentHandler(t *testing.T) {
+	handler := newSwapHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("old"))
+	}))
+	before := httptest.NewRecorder()
+	handler.ServeHTTP(before, httptest.NewRequest("GET", "/", nil))
+
+	handler.swap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("new"))
+	}))
+	after := httptest.NewRecorder()
+	handler.ServeHTTP(after, httptest.NewRequest("GET", "/", nil))
+
+	assert.Equal(t, "old", before.Body.String())
+	assert.Equal(t, "new", after.Body.String())
+}
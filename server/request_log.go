@@ -0,0 +1,96 @@
+package server
+
+import (
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/logs"
+	"github.com/gorilla/mux"
+	"github.com/op/go-logging"
+	"net/http"
+	"os"
+	"time"
+)
+
+var accessLogger = logging.MustGetLogger("Access")
+
+// configureLogging applies the log format and level of the configuration
+func (server *Server) configureLogging() error {
+	return logs.Configure(os.Stderr, server.Config.LogFormat, server.Config.LogLevel)
+}
+
+// requestLogMiddleware gives every request an ID, which is sent in the
+// X-Request-ID header and added to the messages logged for the request.
+// When the request is done, it writes a line to the access log and
+// updates the request metrics
+func requestLogMiddleware(router *mux.Router, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started := time.Now()
+		r = logs.WithRequestID(r, logs.NewRequestID())
+		w.Header().Set(logs.RequestIDHeader, logs.RequestID(r))
+		body := &countingReader{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+		recorder := newResponseRecorder(w)
+		next.ServeHTTP(recorder, r)
+		duration := time.Since(started)
+		route := routeTemplate(router, r)
+		observeRequest(r, route, recorder, body.bytes, duration)
+		if route == "" {
+			route = "-"
+		}
+		logs.Request(r, accessLogger).Infof("%v", logs.Fields{
+			"method":      r.Method,
+			"route":       route,
+			"status":      recorder.status,
+			"bytes":       recorder.bytes,
+			"duration_ms": float64(duration.Nanoseconds()) / float64(time.Millisecond),
+			"user":        logs.User(r),
+		})
+	})
+}
+
+// recordUser passes the logged in user to the access log
+func recordUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logs.SetUser(r, auth.User(r))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeTemplate returns the path template of the route of the router
+// which serves the request. It is empty when no route matches
+func routeTemplate(router *mux.Router, r *http.Request) string {
+	var match mux.RouteMatch
+	if !router.Match(r, &match) || match.Route == nil {
+		return ""
+	}
+	template, _ := match.Route.GetPathTemplate()
+	return template
+}
+
+// responseRecorder remembers the status and the size of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, status: 200}
+}
+
+func (recorder *responseRecorder) WriteHeader(status int) {
+	if !recorder.wroteHeader {
+		recorder.status = status
+		recorder.wroteHeader = true
+	}
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *responseRecorder) Write(content []byte) (int, error) {
+	recorder.wroteHeader = true
+	written, err := recorder.ResponseWriter.Write(content)
+	recorder.bytes += int64(written)
+	return written, err
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/logs"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_requestLogMiddleware_ShouldLogRequestsWithID(t *testing.T) {
+	out := new(bytes.Buffer)
+	logs.Configure(out, logs.FormatJSON, "")
+	defer logs.Configure(os.Stderr, "", "")
+	router := mux.NewRouter()
+	var loggedID string
+	router.HandleFunc("/scan/{dir}/", func(w http.ResponseWriter, r *http.Request) {
+		loggedID = logs.RequestID(r)
+		http.NotFound(w, r)
+	})
+	handler := requestLogMiddleware(router, recordUser(router))
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, auth.WithIdentity(httptest.NewRequest("GET", "/scan/secret/", nil), auth.Identity{Name: "alice"}))
+	var line map[string]interface{}
+	json.Unmarshal(out.Bytes(), &line)
+
+	assert.NotEqual(t, "", loggedID)
+	assert.Equal(t, loggedID, w.Header().Get(logs.RequestIDHeader))
+	assert.Equal(t, loggedID, line["request_id"])
+	assert.Equal(t, "GET", line["method"])
+	assert.Equal(t, "/scan/{dir}/", line["route"])
+	assert.Equal(t, float64(404), line["status"])
+	assert.Equal(t, float64(w.Body.Len()), line["bytes"])
+	assert.Equal(t, "alice", line["user"])
+}
@@ -3,6 +3,7 @@
 package server
 
 import (
+	"bytes"
 	"io/ioutil"
 	"encoding/xml"
 	"github.com/gorilla/mux"
@@ -10,10 +11,31 @@ import (
 	"strconv"
 	"github.com/op/go-logging"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"gopkg.in/yaml.v2"
+	"github.com/doojin/file-explorer/audit"
+	"github.com/doojin/file-explorer/auth"
 	"github.com/doojin/file-explorer/crypto"
 	"github.com/doojin/file-explorer/server/controller"
 	"github.com/doojin/file-explorer/explorer"
+	"github.com/doojin/file-explorer/jobs"
+	"github.com/doojin/file-explorer/pathref"
+	"github.com/doojin/file-explorer/session"
+	"github.com/doojin/file-explorer/share"
+	"github.com/doojin/file-explorer/thumbnail"
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
 )
 
 var logger = logging.MustGetLogger("HTTP Server")
@@ -21,62 +43,326 @@ var logger = logging.MustGetLogger("HTTP Server")
 var cssDir = "./server/templates/resources/css/"
 var imgDir = "./server/templates/resources/img/"
 
+const defaultThumbnailCacheSize = 100 * 1024 * 1024
+
+const defaultSharesFile = "shares.json"
+
+// logoutPath ends the session of the logged in user
+const logoutPath = "/logout/"
+
+// Default timeouts of the HTTP server. Reading request bodies and writing
+// responses isn't limited by default, because uploads and downloads of
+// large files take long. Headers must always arrive in readHeaderTimeout
+const (
+	readHeaderTimeout  = 30 * time.Second
+	defaultIdleTimeout = 2 * time.Minute
+	defaultGracePeriod = 30 * time.Second
+)
+
 // A simple HTTP server
 type Server struct {
 	Config ServerConfig
+	jobs   *jobs.Manager
+	shares *share.Store
+
+	// Login sessions and lockouts are kept when the configuration is
+	// reloaded
+	sessions *auth.Sessions
+	lockout  *auth.Lockout
+	// Password guessing of share links is locked out by share
+	shareLockout *auth.Lockout
+	// The audit log stays open when the configuration is reloaded
+	audit *audit.Log
+
+	// Sources of the configuration, they are read again on reload
+	args          []string
+	environ       []string
+	configFile    string
+	configModTime time.Time
+	handler       *swapHandler
 }
 
-// Start runs server with configuration from server config
-func (server *Server) Start() {
+// Start runs server with configuration from server config until it
+// receives SIGTERM or SIGINT. Requests in flight are given the grace period
+// to finish before the server stops. On SIGHUP, or when the configuration
+// file changes, the configuration is loaded again
+func (server *Server) Start() error {
+	if err := server.Validate(); err != nil {
+		return err
+	}
+	if err := server.configureLogging(); err != nil {
+		return err
+	}
+	handler, err := server.newHandler()
+	if err != nil {
+		return err
+	}
+	if server.audit != nil {
+		defer server.audit.Close()
+	}
+	server.handler = newSwapHandler(handler)
+	httpServer, err := server.httpServer(server.handler)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", server.port())
+	if err != nil {
+		return err
+	}
+	if httpServer.TLSConfig != nil {
+		listener = tls.NewListener(listener, httpServer.TLSConfig)
+		if server.Config.TLS.RedirectPort != 0 {
+			redirectServer := server.redirectServer()
+			redirectListener, err := net.Listen("tcp", ":"+strconv.Itoa(server.Config.TLS.RedirectPort))
+			if err != nil {
+				listener.Close()
+				return err
+			}
+			go redirectServer.Serve(redirectListener)
+			defer redirectServer.Close()
+		}
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(stop)
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+	logger.Infof("HTTP server is starting using port: %v", server.Config.Port)
+	return server.serve(httpServer, listener, stop, reload)
+}
+
+// newHandler creates the router serving all routes
+func (server *Server) newHandler() (http.Handler, error) {
+	encoder, err := server.encoder()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load the encryption key: %v", err)
+	}
+	paths, err := server.pathStrategy(encoder)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set up path references: %v", err)
+	}
 	r := mux.NewRouter()
-	server.registerRoutes(r)
+	// Signed path references contain encoded slashes
+	r.UseEncodedPath()
+	authenticator, err := server.authenticator()
+	if err != nil {
+		return nil, err
+	}
+	policy, err := server.accessPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("access: %v", err)
+	}
+	var owners controller.ExplorerResolver
+	if policy != nil {
+		owners = policy.resolver(authenticator)
+	}
+	if err = server.registerRoutes(r, encoder, paths, owners); err != nil {
+		return nil, err
+	}
+	auditLog, err := server.auditLog()
+	if err != nil {
+		return nil, err
+	}
+	var handler http.Handler = r
+	if policy != nil {
+		handler = policy.middleware(handler)
+	}
+	if server.Config.SessionBoundTokens {
+		handler = session.Middleware(handler)
+	}
+	if auditLog != nil {
+		auditController := controller.NewAuditController(auditLog, server.Config.AuditAdmins)
+		r.HandleFunc("/audit/", auditController.AuditHandler).Methods("GET")
+		handler = auditMiddleware(auditLog, r, handler)
+	}
+	if authenticator != nil {
+		loginController := controller.NewLoginController(authenticator)
+		r.HandleFunc(auth.LoginPath, loginController.LoginFormHandler).Methods("GET")
+		r.HandleFunc(auth.LoginPath, loginController.LoginHandler).Methods("POST")
+		r.HandleFunc(logoutPath, loginController.LogoutHandler).Methods("POST")
+		handler = authenticator.Middleware(recordUser(handler))
+	}
+	return requestLogMiddleware(r, handler), nil
+}
 
-	http.Handle("/", r)
-	logger.Info("HTTP server is starting using port: %v", server.Config.Port)
-	http.ListenAndServe(server.port(), nil)
+// httpServer creates the HTTP server with the configured timeouts
+func (server *Server) httpServer(handler http.Handler) (httpServer *http.Server, err error) {
+	httpServer = &http.Server{Handler: handler, ReadHeaderTimeout: readHeaderTimeout}
+	if httpServer.ReadTimeout, err = parseDuration("readTimeout", server.Config.ReadTimeout, 0); err != nil {
+		return
+	}
+	if httpServer.WriteTimeout, err = parseDuration("writeTimeout", server.Config.WriteTimeout, 0); err != nil {
+		return
+	}
+	if httpServer.IdleTimeout, err = parseDuration("idleTimeout", server.Config.IdleTimeout, defaultIdleTimeout); err != nil {
+		return
+	}
+	if server.Config.TLS.Enabled {
+		httpServer.TLSConfig, err = server.tlsConfig()
+	}
+	return
 }
 
-// LoadXMLConfig fills server config with values from XML file
-func (server *Server) LoadXMLConfig(filename string) {
-	fileContent := server.readConfig(filename)
-	xml.Unmarshal(fileContent, &server.Config)
+// serve handles connections of the listener until a signal is received
+// from stop. Then it stops accepting connections and waits for active
+// requests to finish, at most for the grace period. Signals from reload
+// and changes of the configuration file reload the configuration
+func (server *Server) serve(httpServer *http.Server, listener net.Listener, stop <-chan os.Signal, reload <-chan os.Signal) error {
+	grace, err := parseDuration("shutdownGracePeriod", server.Config.ShutdownGracePeriod, defaultGracePeriod)
+	if err != nil {
+		return err
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+	poll := time.NewTicker(configPollInterval)
+	defer poll.Stop()
+	for stopping := false; !stopping; {
+		select {
+		case err = <-served:
+			return err
+		case received := <-stop:
+			logger.Infof("Received %v, shutting down within %v", received, grace)
+			stopping = true
+		case received := <-reload:
+			logger.Infof("Received %v, reloading the configuration", received)
+			server.reloadAndReport()
+		case <-poll.C:
+			if server.configChanged() {
+				logger.Infof("%v has changed, reloading the configuration", server.configFile)
+				server.reloadAndReport()
+			}
+		}
+		// The grace period is read from the configuration, which may have
+		// been reloaded
+		grace, _ = parseDuration("shutdownGracePeriod", server.Config.ShutdownGracePeriod, defaultGracePeriod)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err = httpServer.Shutdown(ctx); err != nil {
+		httpServer.Close()
+		return fmt.Errorf("requests didn't finish within %v: %v", grace, err)
+	}
+	logger.Info("HTTP server has stopped")
+	return nil
 }
 
-// LoadJSONConfig fills server config with values from JSON file
-func (server *Server) LoadJSONConfig(filename string) {
-	fileContent := server.readConfig(filename)
-	json.Unmarshal(fileContent, &server.Config)
+// LoadXMLConfig fills server config with values from XML file. Unknown
+// elements and attributes are rejected
+func (server *Server) LoadXMLConfig(filename string) error {
+	fileContent, err := server.readConfig(filename)
+	if err != nil {
+		return err
+	}
+	if err = checkXMLKeys(fileContent, reflect.TypeOf(server.Config)); err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+	if err = xml.Unmarshal(fileContent, &server.Config); err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+	return nil
+}
+
+// LoadJSONConfig fills server config with values from JSON file. Unknown
+// keys are rejected
+func (server *Server) LoadJSONConfig(filename string) error {
+	fileContent, err := server.readConfig(filename)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(fileContent))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&server.Config); err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+	return nil
 }
 
-// LoadYAMLConfig fills server config with values from YAML file
-func (server *Server) LoadYAMLConfig(filename string) {
-	fileContent := server.readConfig(filename)
-	yaml.Unmarshal(fileContent, &server.Config)
+// LoadYAMLConfig fills server config with values from YAML file. Unknown
+// keys are rejected
+func (server *Server) LoadYAMLConfig(filename string) error {
+	fileContent, err := server.readConfig(filename)
+	if err != nil {
+		return err
+	}
+	if err = yaml.UnmarshalStrict(fileContent, &server.Config); err != nil {
+		return fmt.Errorf("%v: %v", filename, err)
+	}
+	return nil
 }
 
 func (server *Server) port() string {
 	return ":" + strconv.Itoa(server.Config.Port)
 }
 
-func (server *Server) readConfig(filename string) []byte {
+func (server *Server) readConfig(filename string) ([]byte, error) {
 	fileContent, err := ioutil.ReadFile(filename)
 	if err != nil {
-		logger.Fatalf("Cannot read file %v: %v", filename, err)
+		return nil, fmt.Errorf("cannot read file %v: %v", filename, err)
 	}
-	return fileContent
+	return fileContent, nil
 }
 
-func (server *Server) registerRoutes(router *mux.Router) {
-	key := server.Config.Key
-	encoder, err := crypto.NewEncoder(key)
+func (server *Server) registerRoutes(router *mux.Router, encoder crypto.Encoder, paths pathref.Strategy,
+	owners controller.ExplorerResolver) error {
+	thumbnailer, err := server.thumbnailer()
 	if err != nil {
-		panic(err)
+		return err
 	}
-
+	if server.shares == nil {
+		if server.shares, err = server.shareStore(); err != nil {
+			return err
+		}
+	}
+	rootExplorer := server.rootExplorer()
 	scanDirController := controller.NewScanController(
+		paths,
+		rootExplorer,
+	)
+	downloadController := controller.NewDownloadController(
+		paths,
+		rootExplorer,
+	)
+	previewController := controller.NewPreviewController(
+		paths,
+		rootExplorer,
+	)
+	editorController := controller.NewEditorController(
+		paths,
+		rootExplorer,
+	)
+	compareController := controller.NewCompareController(
+		paths,
+		rootExplorer,
+	)
+	thumbnailController := controller.NewThumbnailController(
+		paths,
+		rootExplorer,
+		thumbnailer,
+	)
+	if server.jobs == nil {
+		server.jobs = jobs.NewManager()
+	}
+	archiveController := controller.NewArchiveController(
+		paths,
+		rootExplorer,
+		server.jobs,
+		explorer.DefaultArchiveLimits,
+	)
+	if server.shareLockout == nil {
+		server.shareLockout = auth.NewLockout()
+	}
+	shareController := controller.NewShareController(
 		encoder,
-		explorer.New(server.Config.RootDir),
+		paths,
+		rootExplorer,
+		server.shares,
+		owners,
+		server.shareLockout,
 	)
+	metricsController := controller.NewMetricsController(server.jobs, thumbnailer)
 
 	router.PathPrefix("/css/").Handler(
 		http.StripPrefix("/css/", http.FileServer(http.Dir(cssDir))))
@@ -86,4 +372,227 @@ func (server *Server) registerRoutes(router *mux.Router) {
 	router.HandleFunc("/", scanDirController.HomeHandler)
 	router.HandleFunc("/scan/{dir}/", scanDirController.ScanHandler)
 	router.HandleFunc("/search/", scanDirController.SearchHandler)
+	router.HandleFunc("/download/{file}/", downloadController.DownloadHandler)
+	router.HandleFunc("/preview/{file}/", previewController.PreviewHandler)
+	router.HandleFunc("/raw/{file}/", previewController.RawHandler)
+	router.HandleFunc("/thumbnail/{file}/", thumbnailController.ThumbnailHandler)
+	router.HandleFunc("/compare/", compareController.CompareHandler)
+	router.HandleFunc("/jobs/", archiveController.JobsHandler)
+	router.HandleFunc("/jobs/{job}/", archiveController.JobHandler)
+	router.HandleFunc("/share/create/{file}/", shareController.CreateFormHandler).Methods("GET")
+	router.HandleFunc("/share/create/{file}/", shareController.CreateHandler).Methods("POST")
+	router.HandleFunc("/shares/", shareController.SharesHandler)
+	router.HandleFunc("/shares/{share}/revoke/", shareController.RevokeHandler).Methods("POST")
+	router.HandleFunc("/s/{token}/", shareController.SharedHandler)
+	router.HandleFunc("/s/{token}/unlock/", shareController.UnlockHandler).Methods("POST")
+	router.HandleFunc("/s/{token}/download/", shareController.SharedDownloadHandler)
+	router.HandleFunc("/metrics", metricsController.MetricsHandler).Methods("GET")
+	if server.Config.ReadOnly {
+		return nil
+	}
+	// Routes which modify files
+	router.HandleFunc("/edit/{file}/", editorController.EditHandler).Methods("GET")
+	router.HandleFunc("/edit/{file}/", editorController.SaveHandler).Methods("POST")
+	router.HandleFunc("/extract/{file}/", archiveController.ExtractHandler).Methods("POST")
+	router.HandleFunc("/pack/{dir}/", archiveController.PackHandler).Methods("POST")
+	router.HandleFunc("/s/{token}/upload/", shareController.SharedUploadHandler).Methods("POST")
+	return nil
+}
+
+// rootExplorer creates the explorer of the whole root with the read-only
+// paths
+func (server *Server) rootExplorer() explorer.Explorer {
+	rootExplorer := explorer.New(server.Config.RootDir)
+	rootExplorer.ReadOnly = server.readOnlyPaths()
+	rootExplorer.Scanned = countScan
+	return rootExplorer
+}
+
+// readOnlyPaths returns the full read-only paths. In read-only mode the
+// whole root is read-only
+func (server *Server) readOnlyPaths() (paths []string) {
+	root := strings.TrimSuffix(server.Config.RootDir, "/")
+	if server.Config.ReadOnly {
+		return []string{server.Config.RootDir}
+	}
+	for _, readOnly := range server.Config.ReadOnlyPaths {
+		paths = append(paths, root+path.Clean("/"+readOnly))
+	}
+	return
+}
+
+func (server *Server) thumbnailer() (*thumbnail.Thumbnailer, error) {
+	dir := server.Config.ThumbnailDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "file-explorer-thumbnails")
+	}
+	size := server.Config.ThumbnailCacheSize
+	if size <= 0 {
+		size = defaultThumbnailCacheSize
+	}
+	concurrency := server.Config.ThumbnailConcurrency
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	cache, err := thumbnail.NewCache(dir, size)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create thumbnail cache %v: %v", dir, err)
+	}
+	return thumbnail.New(cache, concurrency), nil
+}
+
+// authenticator creates the authenticator of the users file. It is nil
+// when no users file is configured
+func (server *Server) authenticator() (*auth.Authenticator, error) {
+	if server.Config.UsersFile == "" {
+		return nil, nil
+	}
+	users, err := auth.LoadUsers(server.Config.UsersFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load users: %v", err)
+	}
+	if server.sessions == nil {
+		idle, err := parseDuration("sessionIdleTimeout", server.Config.SessionIdleTimeout, auth.DefaultIdleTimeout)
+		if err != nil {
+			return nil, err
+		}
+		maxAge, err := parseDuration("sessionMaxAge", server.Config.SessionMaxAge, auth.DefaultMaxAge)
+		if err != nil {
+			return nil, err
+		}
+		server.sessions = auth.NewSessions(idle, maxAge)
+		server.lockout = auth.NewLockout()
+	}
+	return &auth.Authenticator{Users: users, Sessions: server.sessions, Lockout: server.lockout}, nil
+}
+
+func (server *Server) shareStore() (*share.Store, error) {
+	file := server.Config.SharesFile
+	if file == "" {
+		file = defaultSharesFile
+	}
+	store, err := share.NewStore(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load shares from %v: %v", file, err)
+	}
+	return store, nil
+}
+
+// encoder creates the path encoder from the keyring, or from the single
+// key when no keyring is configured
+func (server *Server) encoder() (encoder crypto.Encoder, err error) {
+	var legacyDeadline time.Time
+	if server.Config.LegacyTokensUntil != "" {
+		if legacyDeadline, err = parseTime(server.Config.LegacyTokensUntil); err != nil {
+			return
+		}
+	}
+	if len(server.Config.Keys) == 0 {
+		var key string
+		if key, err = server.key(); err != nil {
+			return
+		}
+		if err = crypto.CheckKey(key); err != nil {
+			return
+		}
+		encoder, err = crypto.NewEncoder(key)
+		if err != nil {
+			return
+		}
+		encoder.AcceptLegacyTokensUntil(legacyDeadline)
+		encoder.UseDeterministicTokens(server.Config.DeterministicTokens)
+		return
+	}
+	var keys []crypto.Key
+	for _, keyConfig := range server.Config.Keys {
+		key := crypto.Key{
+			ID:     keyConfig.ID,
+			Active: keyConfig.Active,
+		}
+		if key.Secret, err = crypto.ParseKey(keyConfig.Value); err != nil {
+			return encoder, fmt.Errorf("key %v: %v", keyConfig.ID, err)
+		}
+		if err = crypto.CheckKey(key.Secret); err != nil {
+			return encoder, fmt.Errorf("key %v: %v", keyConfig.ID, err)
+		}
+		if keyConfig.RetireAt != "" {
+			if key.RetireAt, err = parseTime(keyConfig.RetireAt); err != nil {
+				return
+			}
+		}
+		keys = append(keys, key)
+	}
+	encoder, err = crypto.NewKeyringEncoder(keys)
+	if err != nil {
+		return
+	}
+	encoder.AcceptLegacyTokensUntil(legacyDeadline)
+	encoder.UseDeterministicTokens(server.Config.DeterministicTokens)
+	for _, id := range encoder.RetiredKeys() {
+		logger.Warningf("Key %v is retired and can be removed from the configuration", id)
+	}
+	return
+}
+
+// pathStrategy creates the configured strategy for path references
+func (server *Server) pathStrategy(encoder crypto.Encoder) (paths pathref.Strategy, err error) {
+	switch server.Config.PathReferences {
+	case "", "encrypted":
+		paths = pathref.NewEncrypted(encoder)
+	case "signed":
+		paths = pathref.NewSigned(server.Config.RootDir, encoder)
+	case "registry":
+		if paths, err = pathref.NewRegistry(server.Config.RootDir, server.Config.PathRegistryFile); err != nil {
+			return
+		}
+	default:
+		return nil, fmt.Errorf("unknown path references %q", server.Config.PathReferences)
+	}
+	if _, ok := paths.(pathref.Binder); server.Config.SessionBoundTokens && !ok {
+		return nil, fmt.Errorf("%v path references can't be bound to sessions", server.Config.PathReferences)
+	}
+	return
+}
+
+// key returns the single key from the configured source
+func (server *Server) key() (key string, err error) {
+	config := server.Config
+	sources := 0
+	for _, source := range []string{config.Key, config.KeyFile, config.KeyEnv, config.Passphrase} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return "", errors.New("only one of key, keyFile, keyEnv and passphrase can be set")
+	}
+	switch {
+	case config.KeyFile != "":
+		return crypto.ReadKeyFile(config.KeyFile)
+	case config.KeyEnv != "":
+		return crypto.KeyFromEnv(config.KeyEnv)
+	case config.Passphrase != "":
+		return crypto.PassphraseKey(config.Passphrase, config.PassphraseSalt)
+	}
+	return crypto.ParseKey(config.Key)
+}
+
+// parseDuration parses the duration setting, empty settings have the
+// default value
+func parseDuration(name string, value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%v must be a duration like 30s or 5m, got %q", name, value)
+	}
+	return duration, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.RFC3339, value)
 }
\ No newline at end of file
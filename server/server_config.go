@@ -3,8 +3,134 @@ package server
 // ServerConfig contains configuration settings for running
 // HTTP server
 type ServerConfig struct {
-	Port            int    	`xml:"port" 			json:"port"`
-	Key             string 	`xml:"key" 				json:"key"`
-	RootDir         string 	`xml:"root" 			json:"root"`
-	GoroutineLevels int 	`xml:"goroutineLevels" 	json:"goroutineLevels`
-}
\ No newline at end of file
+	Port            int    `xml:"port" json:"port" yaml:"port"`
+	Key             string `xml:"key" json:"key" yaml:"key"`
+	RootDir         string `xml:"root" json:"root" yaml:"root"`
+	GoroutineLevels int    `xml:"goroutineLevels" json:"goroutineLevels" yaml:"goroutineLevels"`
+
+	// Timeouts of the HTTP server and the time given to requests in flight
+	// on shutdown, as durations like "30s". Empty values use the defaults
+	ReadTimeout         string `xml:"readTimeout" json:"readTimeout" yaml:"readTimeout"`
+	WriteTimeout        string `xml:"writeTimeout" json:"writeTimeout" yaml:"writeTimeout"`
+	IdleTimeout         string `xml:"idleTimeout" json:"idleTimeout" yaml:"idleTimeout"`
+	ShutdownGracePeriod string `xml:"shutdownGracePeriod" json:"shutdownGracePeriod" yaml:"shutdownGracePeriod"`
+
+	TLS TLSConfig `xml:"tls" json:"tls" yaml:"tls"`
+
+	// Thumbnails of images are cached in ThumbnailDir up to
+	// ThumbnailCacheSize bytes, at most ThumbnailConcurrency thumbnails
+	// are generated at the same time
+	ThumbnailDir         string `xml:"thumbnailDir" json:"thumbnailDir" yaml:"thumbnailDir"`
+	ThumbnailCacheSize   int64  `xml:"thumbnailCacheSize" json:"thumbnailCacheSize" yaml:"thumbnailCacheSize"`
+	ThumbnailConcurrency int    `xml:"thumbnailConcurrency" json:"thumbnailConcurrency" yaml:"thumbnailConcurrency"`
+
+	// Instead of Key, the key can be read from KeyFile or from the
+	// environment variable KeyEnv, or derived from Passphrase with
+	// PassphraseSalt. Only one of them can be set
+	KeyFile        string `xml:"keyFile" json:"keyFile" yaml:"keyFile"`
+	KeyEnv         string `xml:"keyEnv" json:"keyEnv" yaml:"keyEnv"`
+	Passphrase     string `xml:"passphrase" json:"passphrase" yaml:"passphrase"`
+	PassphraseSalt string `xml:"passphraseSalt" json:"passphraseSalt" yaml:"passphraseSalt"`
+
+	// Keys replace Key when encryption keys are rotated
+	Keys []KeyConfig `xml:"keys>key" json:"keys" yaml:"keys"`
+	// LegacyTokensUntil is a date or time until which links with tokens
+	// in the old "$" format are accepted. Empty means no limit
+	LegacyTokensUntil string `xml:"legacyTokensUntil" json:"legacyTokensUntil" yaml:"legacyTokensUntil"`
+	// DeterministicTokens gives every path the same token under a key, so
+	// links can be bookmarked and cached
+	DeterministicTokens bool `xml:"deterministicTokens" json:"deterministicTokens" yaml:"deterministicTokens"`
+
+	// PathReferences chooses how paths appear in URLs: "encrypted" tokens
+	// (default), "signed" readable relative paths, or "registry" short IDs
+	// kept in PathRegistryFile. The registry keeps every path ever listed
+	// and is never trimmed, so it only suits trees of moderate size
+	PathReferences   string `xml:"pathReferences" json:"pathReferences" yaml:"pathReferences"`
+	PathRegistryFile string `xml:"pathRegistryFile" json:"pathRegistryFile" yaml:"pathRegistryFile"`
+	// SessionBoundTokens binds encrypted path tokens to a session cookie,
+	// so that copied links don't work in other browsers. With UsersFile
+	// tokens are bound to the logged in user instead. Share links are not
+	// affected
+	SessionBoundTokens bool `xml:"sessionBoundTokens" json:"sessionBoundTokens" yaml:"sessionBoundTokens"`
+
+	// UsersFile lists the users who can log in, as name:hash lines with
+	// bcrypt hashes. Without it no login is required. Login sessions end
+	// after SessionIdleTimeout without requests and after SessionMaxAge
+	UsersFile          string `xml:"usersFile" json:"usersFile" yaml:"usersFile"`
+	SessionIdleTimeout string `xml:"sessionIdleTimeout" json:"sessionIdleTimeout" yaml:"sessionIdleTimeout"`
+	SessionMaxAge      string `xml:"sessionMaxAge" json:"sessionMaxAge" yaml:"sessionMaxAge"`
+
+	// ReadOnly disables every route which modifies files. ReadOnlyPaths
+	// are paths relative to the root below which nothing can be written,
+	// also when ReadOnly is not set
+	ReadOnly      bool     `xml:"readOnly" json:"readOnly" yaml:"readOnly"`
+	ReadOnlyPaths []string `xml:"readOnlyPaths>path" json:"readOnlyPaths" yaml:"readOnlyPaths"`
+
+	// Access gives users and groups their own roots and access rules.
+	// Without it every user sees the whole root
+	Access AccessConfig `xml:"access" json:"access" yaml:"access"`
+
+	// LogFormat is "text" (default) or "json". LogLevel is one of debug,
+	// info (default), notice, warning, error and critical
+	LogFormat string `xml:"logFormat" json:"logFormat" yaml:"logFormat"`
+	LogLevel  string `xml:"logLevel" json:"logLevel" yaml:"logLevel"`
+
+	// AuditFile is the audit log of all access and modifications as JSON
+	// lines. It is rotated at AuditMaxSize bytes (10 MB by default) and
+	// AuditBackups rotated files are kept (5 by default). AuditAdmins are
+	// the users and groups who can see the log at /audit/
+	AuditFile    string   `xml:"auditFile" json:"auditFile" yaml:"auditFile"`
+	AuditMaxSize int64    `xml:"auditMaxSize" json:"auditMaxSize" yaml:"auditMaxSize"`
+	AuditBackups int      `xml:"auditBackups" json:"auditBackups" yaml:"auditBackups"`
+	AuditAdmins  []string `xml:"auditAdmins>admin" json:"auditAdmins" yaml:"auditAdmins"`
+
+	// SharesFile keeps the share links, shares.json in the working
+	// directory by default
+	SharesFile string `xml:"sharesFile" json:"sharesFile" yaml:"sharesFile"`
+}
+
+// KeyConfig describes a key of the keyring. RetireAt is a date
+// (2006-01-02) or time (RFC 3339) after which the key is not accepted
+type KeyConfig struct {
+	ID       string `xml:"id,attr" json:"id" yaml:"id"`
+	Value    string `xml:",chardata" json:"key" yaml:"key"`
+	Active   bool   `xml:"active,attr" json:"active" yaml:"active"`
+	RetireAt string `xml:"retire,attr" json:"retire" yaml:"retire"`
+}
+
+// TLSConfig describes HTTPS settings. Without Cert and Key a self-signed
+// certificate is generated. MinVersion is one of "1.0", "1.1", "1.2"
+// (default) and "1.3". When RedirectPort is set, plain HTTP requests on
+// that port are redirected to HTTPS
+type TLSConfig struct {
+	Enabled      bool   `xml:"enabled,attr" json:"enabled" yaml:"enabled"`
+	Cert         string `xml:"cert" json:"cert" yaml:"cert"`
+	Key          string `xml:"key" json:"key" yaml:"key"`
+	MinVersion   string `xml:"minVersion" json:"minVersion" yaml:"minVersion"`
+	RedirectPort int    `xml:"redirectPort" json:"redirectPort" yaml:"redirectPort"`
+}
+
+// AccessConfig lists the roots and rules of users and groups. A user gets
+// the root of the user entry, or else of the first group entry with a
+// root, and the rules of all of them. Users without entries have no access
+type AccessConfig struct {
+	Users  []AccessEntry `xml:"user" json:"users" yaml:"users"`
+	Groups []AccessEntry `xml:"group" json:"groups" yaml:"groups"`
+}
+
+// AccessEntry is the root and the rules of a user or group. Root is
+// relative to the root of the server, rule paths are relative to the root
+// of the user
+type AccessEntry struct {
+	Name  string       `xml:"name,attr" json:"name" yaml:"name"`
+	Root  string       `xml:"root,attr" json:"root" yaml:"root"`
+	Rules []RuleConfig `xml:"rule" json:"rules" yaml:"rules"`
+}
+
+// RuleConfig allows and denies comma separated permissions (read, write,
+// share or all) on a path and everything below it
+type RuleConfig struct {
+	Path  string `xml:"path,attr" json:"path" yaml:"path"`
+	Allow string `xml:"allow,attr" json:"allow" yaml:"allow"`
+	Deny  string `xml:"deny,attr" json:"deny" yaml:"deny"`
+}
@@ -1,10 +1,25 @@
 package server
 
 import (
+	"bytes"
+	"fmt"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/server/controller"
+	"mime/multipart"
+	"strings"
 	"testing"
 	"github.com/stretchr/testify/assert"
+	"github.com/doojin/file-explorer/crypto"
+	"github.com/doojin/file-explorer/share"
+	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"syscall"
+	"time"
 )
 
 func Test_LoadXMLConfig_ShouldLoadValuesFromXMLFile(t *testing.T) {
@@ -61,11 +76,40 @@ func Test_LoadYAMLConfig_IfConfigDoesntContainValuesTheDefaultValuesShouldBeSet(
 	deleteConfigFile("config.yaml")
 }
 
+func Test_LoadYAMLConfig_ShouldUseDocumentedKeys(t *testing.T) {
+	configContent := "root: /srv\nreadTimeout: 30s\n" +
+		"tls:\n  enabled: true\n  minVersion: \"1.2\"\n" +
+		"keys:\n  - id: \"2017\"\n    key: abcdefghijklmnop\n    active: true\n" +
+		"access:\n  users:\n    - name: alice\n      root: home/alice\n" +
+		"      rules:\n        - path: /private\n          deny: share\n"
+	createConfigFile("config.yaml", configContent)
+	defer deleteConfigFile("config.yaml")
+	server := Server{}
+
+	err := server.LoadYAMLConfig("config.yaml")
+	content, _ := yaml.Marshal(server.Config)
+	roundTrip := Server{}
+	roundTripErr := yaml.UnmarshalStrict(content, &roundTrip.Config)
+	roundTripContent, _ := yaml.Marshal(roundTrip.Config)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "/srv", server.Config.RootDir)
+	assert.Equal(t, "30s", server.Config.ReadTimeout)
+	assert.Equal(t, TLSConfig{Enabled: true, MinVersion: "1.2"}, server.Config.TLS)
+	assert.Equal(t, []KeyConfig{{ID: "2017", Value: "abcdefghijklmnop", Active: true}}, server.Config.Keys)
+	assert.Equal(t, []AccessEntry{{Name: "alice", Root: "home/alice",
+		Rules: []RuleConfig{{Path: "/private", Deny: "share"}}}}, server.Config.Access.Users)
+	assert.Equal(t, nil, roundTripErr)
+	assert.Contains(t, string(content), "readTimeout: 30s")
+	assert.Equal(t, string(content), string(roundTripContent))
+}
+
 func Test_readConfig_ShouldReturnFileContent(t *testing.T) {
 	server := Server{}
 	fileContent := "file content string"
 	createConfigFile("config.cfg", fileContent)
-	actualFileContent := server.readConfig("config.cfg")
+	actualFileContent, err := server.readConfig("config.cfg")
+	assert.Equal(t, nil, err)
 	assert.Equal(t, []byte("file content string"), actualFileContent)
 	deleteConfigFile("config.cfg")
 }
@@ -92,4 +136,240 @@ func deleteConfigFile(filename string) {
 	if err != nil {
 		logger.Fatal(err)
 	}
-}
\ No newline at end of file
+}
+
+func Test_LoadXMLConfig_ShouldLoadKeyring(t *testing.T) {
+	configContent := "<config><keys>" +
+		"<key id=\"2016\" retire=\"2017-02-01\">1234567890123456</key>" +
+		"<key id=\"2017\" active=\"true\">abcdefghijklmnop</key>" +
+		"</keys></config>"
+	createConfigFile("config.xml", configContent)
+	server := Server{}
+	server.LoadXMLConfig("config.xml")
+	assert.Equal(t, []KeyConfig{
+		KeyConfig{ID: "2016", Value: "1234567890123456", RetireAt: "2017-02-01"},
+		KeyConfig{ID: "2017", Value: "abcdefghijklmnop", Active: true},
+	}, server.Config.Keys)
+	deleteConfigFile("config.xml")
+}
+
+func Test_encoder_ShouldDecryptTokensOfRotatedKeys(t *testing.T) {
+	oldServer := Server{Config: ServerConfig{Key: "k3J9xQ2mP7vR4tW8"}}
+	newServer := Server{Config: ServerConfig{Keys: []KeyConfig{
+		KeyConfig{ID: "0", Value: "k3J9xQ2mP7vR4tW8", RetireAt: "2100-01-01"},
+		KeyConfig{ID: "1", Value: "Zp5Lq8Nc1Vb6Hs0D", Active: true},
+	}}}
+	oldEncoder, _ := oldServer.encoder()
+	newEncoder, err := newServer.encoder()
+	token, _ := oldEncoder.Encrypt("C:/share")
+
+	path, decryptErr := newEncoder.Decrypt(token)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, nil, decryptErr)
+	assert.Equal(t, "C:/share", path)
+}
+
+func Test_encoder_ShouldReturnErrorForInvalidRetireDate(t *testing.T) {
+	server := Server{Config: ServerConfig{Keys: []KeyConfig{
+		KeyConfig{ID: "0", Value: "k3J9xQ2mP7vR4tW8", RetireAt: "soon"},
+	}}}
+
+	_, err := server.encoder()
+
+	assert.NotEqual(t, nil, err)
+}
+
+func Test_encoder_ShouldRejectWeakKeys(t *testing.T) {
+	server := Server{Config: ServerConfig{Key: "1234567890123456"}}
+	keyringServer := Server{Config: ServerConfig{Keys: []KeyConfig{
+		KeyConfig{ID: "0", Value: "k3J9xQ2mP7vR4tW8", Active: true},
+		KeyConfig{ID: "1", Value: "abcdefghijklmnop"},
+	}}}
+
+	_, err := server.encoder()
+	_, keyringErr := keyringServer.encoder()
+
+	assert.Equal(t, crypto.ERR_WEAK_KEY, err)
+	assert.NotEqual(t, nil, keyringErr)
+}
+
+func Test_encoder_ShouldRejectSeveralKeySources(t *testing.T) {
+	server := Server{Config: ServerConfig{Key: "k3J9xQ2mP7vR4tW8", KeyEnv: "FILE_EXPLORER_KEY"}}
+
+	_, err := server.encoder()
+
+	assert.NotEqual(t, nil, err)
+}
+
+func Test_encoder_ShouldReturnErrorForInvalidKeyLength(t *testing.T) {
+	server := Server{Config: ServerConfig{Key: "k3J9xQ2mP7vR4tW"}}
+
+	_, err := server.encoder()
+
+	assert.NotEqual(t, nil, err)
+}
+
+func Test_pathStrategy_ShouldRejectSessionBindingOfUnboundReferences(t *testing.T) {
+	encoder, _ := crypto.NewEncoder("k3J9xQ2mP7vR4tW8")
+	server := Server{Config: ServerConfig{PathReferences: "registry", SessionBoundTokens: true}}
+	encryptedServer := Server{Config: ServerConfig{SessionBoundTokens: true}}
+
+	_, err := server.pathStrategy(encoder)
+	_, encryptedErr := encryptedServer.pathStrategy(encoder)
+
+	assert.NotEqual(t, nil, err)
+	assert.Equal(t, nil, encryptedErr)
+}
+
+func Test_httpServer_ShouldUseConfiguredTimeouts(t *testing.T) {
+	server := Server{Config: ServerConfig{ReadTimeout: "10s", IdleTimeout: "1m"}}
+	invalidServer := Server{Config: ServerConfig{WriteTimeout: "ten seconds"}}
+
+	httpServer, err := server.httpServer(http.NotFoundHandler())
+	_, invalidErr := invalidServer.httpServer(http.NotFoundHandler())
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 10*time.Second, httpServer.ReadTimeout)
+	assert.Equal(t, time.Duration(0), httpServer.WriteTimeout)
+	assert.Equal(t, time.Minute, httpServer.IdleTimeout)
+	assert.NotEqual(t, nil, invalidErr)
+}
+
+func Test_serve_ShouldFinishRequestsInFlightOnShutdown(t *testing.T) {
+	server := Server{Config: ServerConfig{ShutdownGracePeriod: "5s"}}
+	started := make(chan bool)
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	})}
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() { served <- server.serve(httpServer, listener, stop, nil) }()
+	responses := make(chan string, 1)
+	go func() {
+		response, err := http.Get("http://" + listener.Addr().String() + "/")
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		responses <- string(body)
+	}()
+
+	<-started
+	stop <- syscall.SIGTERM
+
+	assert.Equal(t, "done", <-responses)
+	assert.Equal(t, nil, <-served)
+	_, err := http.Get("http://" + listener.Addr().String() + "/")
+	assert.NotEqual(t, nil, err)
+}
+
+func Test_serve_ShouldReturnErrorIfGracePeriodIsExceeded(t *testing.T) {
+	server := Server{Config: ServerConfig{ShutdownGracePeriod: "50ms"}}
+	started := make(chan bool)
+	httpServer := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		time.Sleep(time.Second)
+	})}
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() { served <- server.serve(httpServer, listener, stop, nil) }()
+	go http.Get("http://" + listener.Addr().String() + "/")
+
+	<-started
+	stop <- syscall.SIGTERM
+
+	assert.NotEqual(t, nil, <-served)
+}
+
+func Test_newHandler_ShouldNotRouteModificationsInReadOnlyMode(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "read-only")
+	defer os.RemoveAll(dir)
+	config := ServerConfig{
+		RootDir:      dir,
+		Key:          "k3J9xQ2mP7vR4tW8",
+		SharesFile:   filepath.Join(dir, "shares.json"),
+		ThumbnailDir: filepath.Join(dir, "thumbnails"),
+	}
+	writable := Server{Config: config}
+	writableHandler, _ := writable.newHandler()
+	config.ReadOnly = true
+	readOnly := Server{Config: config, shares: writable.shares}
+	readOnlyHandler, err := readOnly.newHandler()
+	// Share links are checked before uploads, so a valid one is needed
+	encoder, _ := writable.encoder()
+	uploadShare, _ := writable.shares.Create("", dir, share.ScopeUpload, time.Now().Add(time.Hour), "", 0)
+
+	assert.Equal(t, nil, err)
+	routes := []struct{ method, path string }{
+		{"GET", "/edit/token/"},
+		{"POST", "/edit/token/"},
+		{"POST", "/extract/token/"},
+		{"POST", "/pack/token/"},
+		{"POST", "/s/" + share.Token(encoder, uploadShare) + "/upload/"},
+	}
+	for _, route := range routes {
+		writableW := httptest.NewRecorder()
+		writableHandler.ServeHTTP(writableW, httptest.NewRequest(route.method, route.path, nil))
+		readOnlyW := httptest.NewRecorder()
+		readOnlyHandler.ServeHTTP(readOnlyW, httptest.NewRequest(route.method, route.path, nil))
+
+		assert.NotEqual(t, 404, writableW.Code, route.path)
+		assert.Equal(t, 404, readOnlyW.Code, route.path)
+	}
+	assert.Equal(t, []string{dir}, readOnly.rootExplorer().ReadOnly)
+}
+
+func Test_newHandler_ShouldLimitShareUploadsAndUnlocks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "share-limits")
+	defer os.RemoveAll(dir)
+	server := Server{Config: ServerConfig{
+		RootDir:      dir,
+		Key:          "k3J9xQ2mP7vR4tW8",
+		SharesFile:   filepath.Join(dir, "shares.json"),
+		ThumbnailDir: filepath.Join(dir, "thumbnails"),
+	}}
+	handler, _ := server.newHandler()
+	encoder, _ := server.encoder()
+	os.Mkdir(filepath.Join(dir, "in"), 0777)
+	uploadShare, _ := server.shares.Create("", filepath.Join(dir, "in"), share.ScopeUpload, time.Now().Add(time.Hour), "", 0)
+	lockedShare, _ := server.shares.Create("", dir, share.ScopeRead, time.Now().Add(time.Hour), "secret", 0)
+	for i := 0; i < auth.MaxFailures; i++ {
+		server.shareLockout.Fail(lockedShare.ID)
+	}
+
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	for i := 0; i <= controller.MaxShareUploadFiles; i++ {
+		part, _ := form.CreateFormFile("file", fmt.Sprintf("%v.txt", i))
+		part.Write([]byte("content"))
+	}
+	form.Close()
+	upload := httptest.NewRequest("POST", "/s/"+share.Token(encoder, uploadShare)+"/upload/", body)
+	upload.Header.Set("Content-Type", form.FormDataContentType())
+	uploadW := httptest.NewRecorder()
+	handler.ServeHTTP(uploadW, upload)
+	unlock := httptest.NewRequest("POST", "/s/"+share.Token(encoder, lockedShare)+"/unlock/",
+		strings.NewReader("password=secret"))
+	unlock.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	unlockW := httptest.NewRecorder()
+	handler.ServeHTTP(unlockW, unlock)
+	uploaded, _ := ioutil.ReadDir(filepath.Join(dir, "in"))
+
+	assert.Equal(t, 413, uploadW.Code)
+	assert.Equal(t, controller.MaxShareUploadFiles, len(uploaded))
+	assert.Equal(t, 429, unlockW.Code)
+	assert.Equal(t, 0, len(unlockW.Result().Cookies()))
+}
+
+func Test_readOnlyPaths_ShouldBeBelowTheRoot(t *testing.T) {
+	server := Server{Config: ServerConfig{RootDir: "/srv/files/", ReadOnlyPaths: []string{"archive", "/../etc/"}}}
+
+	assert.Equal(t, []string{"/srv/files/archive", "/srv/files/etc"}, server.readOnlyPaths())
+}
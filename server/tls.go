@@ -0,0 +1,171 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// selfSignedValidity is the validity of generated self-signed certificates
+const selfSignedValidity = 365 * 24 * time.Hour
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsConfig creates the TLS configuration of the server. Certificates are
+// loaded from the configured files, without them a self-signed certificate
+// is generated
+func (server *Server) tlsConfig() (config *tls.Config, err error) {
+	settings := server.Config.TLS
+	config = &tls.Config{MinVersion: tls.VersionTLS12}
+	if settings.MinVersion != "" {
+		version, ok := tlsVersions[settings.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version %q, use one of 1.0, 1.1, 1.2 and 1.3", settings.MinVersion)
+		}
+		config.MinVersion = version
+	}
+	if settings.Cert == "" && settings.Key == "" {
+		logger.Warning("No TLS certificate is configured, using a self-signed certificate")
+		certificate, err := selfSignedCertificate()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{certificate}
+		return config, nil
+	}
+	if settings.Cert == "" || settings.Key == "" {
+		return nil, fmt.Errorf("both TLS cert and key files must be set")
+	}
+	reloader, err := newCertReloader(settings.Cert, settings.Key)
+	if err != nil {
+		return nil, err
+	}
+	config.GetCertificate = reloader.GetCertificate
+	return config, nil
+}
+
+// redirectServer creates the HTTP server which redirects all requests to
+// HTTPS
+func (server *Server) redirectServer() *http.Server {
+	port := strconv.Itoa(server.Config.Port)
+	return &http.Server{
+		ReadHeaderTimeout: readHeaderTimeout,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				host = r.Host
+			}
+			if port != "443" {
+				host = net.JoinHostPort(host, port)
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), 301)
+		}),
+	}
+}
+
+// certReloader serves the certificate from the files and loads it again
+// when the files change, so renewed certificates are used without restart
+type certReloader struct {
+	mutex       sync.Mutex
+	certFile    string
+	keyFile     string
+	certificate *tls.Certificate
+	modTime     time.Time
+}
+
+func newCertReloader(certFile string, keyFile string) (reloader *certReloader, err error) {
+	reloader = &certReloader{certFile: certFile, keyFile: keyFile}
+	if err = reloader.reload(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// GetCertificate returns the current certificate. It is used for every
+// TLS handshake
+func (reloader *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+	if !reloader.lastModified().Equal(reloader.modTime) {
+		if err := reloader.reload(); err != nil {
+			// Files can be seen in the middle of an update, the old
+			// certificate is used until both files are complete
+			logger.Warningf("Cannot reload TLS certificate: %v", err)
+		} else {
+			logger.Infof("TLS certificate was reloaded from %v", reloader.certFile)
+		}
+	}
+	return reloader.certificate, nil
+}
+
+func (reloader *certReloader) reload() error {
+	modTime := reloader.lastModified()
+	certificate, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return err
+	}
+	reloader.certificate = &certificate
+	reloader.modTime = modTime
+	return nil
+}
+
+// lastModified returns the last modification time of the certificate and
+// key files
+func (reloader *certReloader) lastModified() (modTime time.Time) {
+	for _, file := range []string{reloader.certFile, reloader.keyFile} {
+		if info, err := os.Stat(file); err == nil && info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	return
+}
+
+// selfSignedCertificate generates a certificate for localhost and the host
+// name of the machine. Browsers warn about such certificates, they are
+// meant for labs and tests only
+func selfSignedCertificate() (certificate tls.Certificate, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return
+	}
+	hosts := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil {
+		hosts = append(hosts, hostname)
+	}
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"File Explorer"}, CommonName: hosts[len(hosts)-1]},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              hosts,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
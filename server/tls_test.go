@@ -0,0 +1,74 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func writeCertificate(certificate tls.Certificate, certFile string, keyFile string, modTime time.Time) {
+	key, _ := x509.MarshalECPrivateKey(certificate.PrivateKey.(*ecdsa.PrivateKey))
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate.Certificate[0]}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key}), 0600)
+	os.Chtimes(certFile, modTime, modTime)
+	os.Chtimes(keyFile, modTime, modTime)
+}
+
+func Test_tlsConfig_ShouldUseSelfSignedCertificateWithoutFiles(t *testing.T) {
+	server := Server{Config: ServerConfig{TLS: TLSConfig{Enabled: true, MinVersion: "1.3"}}}
+	invalidServer := Server{Config: ServerConfig{TLS: TLSConfig{Enabled: true, MinVersion: "3"}}}
+	halfServer := Server{Config: ServerConfig{TLS: TLSConfig{Enabled: true, Cert: "cert.pem"}}}
+
+	config, err := server.tlsConfig()
+	_, invalidErr := invalidServer.tlsConfig()
+	_, halfErr := halfServer.tlsConfig()
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+	assert.Equal(t, 1, len(config.Certificates))
+	leaf, _ := x509.ParseCertificate(config.Certificates[0].Certificate[0])
+	assert.Equal(t, nil, leaf.VerifyHostname("localhost"))
+	assert.NotEqual(t, nil, invalidErr)
+	assert.NotEqual(t, nil, halfErr)
+}
+
+func Test_certReloader_ShouldReloadChangedCertificate(t *testing.T) {
+	defer os.Remove("cert_test.pem")
+	defer os.Remove("key_test.pem")
+	first, _ := selfSignedCertificate()
+	second, _ := selfSignedCertificate()
+	writeCertificate(first, "cert_test.pem", "key_test.pem", time.Now().Add(-time.Hour))
+	reloader, err := newCertReloader("cert_test.pem", "key_test.pem")
+
+	loaded, _ := reloader.GetCertificate(nil)
+	writeCertificate(second, "cert_test.pem", "key_test.pem", time.Now())
+	reloaded, _ := reloader.GetCertificate(nil)
+	ioutil.WriteFile("key_test.pem", []byte("incomplete"), 0600)
+	kept, _ := reloader.GetCertificate(nil)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, first.Certificate[0], loaded.Certificate[0])
+	assert.Equal(t, second.Certificate[0], reloaded.Certificate[0])
+	assert.Equal(t, second.Certificate[0], kept.Certificate[0])
+}
+
+func Test_redirectServer_ShouldRedirectToHTTPS(t *testing.T) {
+	server := Server{Config: ServerConfig{Port: 8443}}
+	defaultPortServer := Server{Config: ServerConfig{Port: 443}}
+	w := httptest.NewRecorder()
+	defaultPortW := httptest.NewRecorder()
+
+	server.redirectServer().Handler.ServeHTTP(w, httptest.NewRequest("GET", "http://files.local:8080/scan/abc/?view=grid", nil))
+	defaultPortServer.redirectServer().Handler.ServeHTTP(defaultPortW, httptest.NewRequest("GET", "http://files.local/", nil))
+
+	assert.Equal(t, 301, w.Code)
+	assert.Equal(t, "https://files.local:8443/scan/abc/?view=grid", w.Header().Get("Location"))
+	assert.Equal(t, "https://files.local/", defaultPortW.Header().Get("Location"))
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/xml"
+	"fmt"
+	"github.com/doojin/file-explorer/auth"
+	"github.com/doojin/file-explorer/logs"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ConfigError lists every problem found in the configuration
+type ConfigError struct {
+	Problems []string
+}
+
+func (err ConfigError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(err.Problems, "\n  - ")
+}
+
+// Validate checks the whole configuration and returns ConfigError with all
+// problems found
+func (server *Server) Validate() error {
+	config := server.Config
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if config.Port < 1 || config.Port > 65535 {
+		problem("port must be between 1 and 65535, got %v", config.Port)
+	}
+	if config.RootDir == "" {
+		problem("root is not set")
+	} else if info, err := os.Stat(config.RootDir); err != nil {
+		problem("root %v doesn't exist", config.RootDir)
+	} else if !info.IsDir() {
+		problem("root %v is not a directory", config.RootDir)
+	}
+	if config.GoroutineLevels < 0 {
+		problem("goroutineLevels can't be negative")
+	}
+
+	if encoder, err := server.encoder(); err != nil {
+		problem("key: %v", err)
+	} else if _, err = server.pathStrategy(encoder); err != nil {
+		problem("pathReferences: %v", err)
+	}
+
+	durations := []struct{ name, value string }{
+		{"readTimeout", config.ReadTimeout},
+		{"writeTimeout", config.WriteTimeout},
+		{"idleTimeout", config.IdleTimeout},
+		{"shutdownGracePeriod", config.ShutdownGracePeriod},
+		{"sessionIdleTimeout", config.SessionIdleTimeout},
+		{"sessionMaxAge", config.SessionMaxAge},
+	}
+	for _, duration := range durations {
+		if _, err := parseDuration(duration.name, duration.value, 0); err != nil {
+			problem("%v", err)
+		}
+	}
+
+	if config.UsersFile != "" {
+		if _, err := auth.LoadUsers(config.UsersFile); err != nil {
+			problem("usersFile: %v", err)
+		}
+	}
+
+	if policy, err := server.accessPolicy(); err != nil {
+		problem("access: %v", err)
+	} else if policy != nil && config.UsersFile == "" {
+		problem("access requires usersFile to identify users")
+	}
+
+	if len(config.AuditAdmins) > 0 && config.UsersFile == "" {
+		problem("auditAdmins requires usersFile to identify users")
+	}
+	if config.AuditMaxSize < 0 {
+		problem("auditMaxSize can't be negative")
+	}
+	if config.AuditBackups < 0 {
+		problem("auditBackups can't be negative")
+	}
+
+	if _, err := logs.ParseLevel(config.LogLevel); err != nil {
+		problem("logLevel: %v", err)
+	}
+	if err := logs.CheckFormat(config.LogFormat); err != nil {
+		problem("logFormat: %v", err)
+	}
+
+	if config.ThumbnailCacheSize < 0 {
+		problem("thumbnailCacheSize can't be negative")
+	}
+	if config.ThumbnailConcurrency < 0 {
+		problem("thumbnailConcurrency can't be negative")
+	}
+
+	if config.TLS.Enabled {
+		if _, ok := tlsVersions[config.TLS.MinVersion]; config.TLS.MinVersion != "" && !ok {
+			problem("tls minVersion must be one of 1.0, 1.1, 1.2 and 1.3, got %q", config.TLS.MinVersion)
+		}
+		if (config.TLS.Cert == "") != (config.TLS.Key == "") {
+			problem("tls cert and key must be set together")
+		} else if config.TLS.Cert != "" {
+			if _, err := tls.LoadX509KeyPair(config.TLS.Cert, config.TLS.Key); err != nil {
+				problem("tls: %v", err)
+			}
+		}
+		if config.TLS.RedirectPort < 0 || config.TLS.RedirectPort > 65535 || config.TLS.RedirectPort == config.Port {
+			problem("tls redirectPort must be between 1 and 65535 and differ from port, got %v", config.TLS.RedirectPort)
+		}
+	}
+
+	if len(problems) > 0 {
+		return ConfigError{problems}
+	}
+	return nil
+}
+
+// checkXMLKeys returns an error for elements and attributes of the XML
+// document which don't match any field of the configuration type
+func checkXMLKeys(content []byte, configType reflect.Type) error {
+	known := map[string]bool{}
+	xmlKeys(configType, "", known)
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var path []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			// The name of the root element is not checked
+			if len(path) > 0 {
+				path = append(path, element.Name.Local)
+			} else {
+				path = append(path, "")
+			}
+			key := strings.Join(path[1:], ">")
+			if len(path) > 1 && !known[key] {
+				return fmt.Errorf("unknown element <%v>", strings.Join(path[1:], "><"))
+			}
+			for _, attr := range element.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				if !known[key+"@"+attr.Name.Local] {
+					return fmt.Errorf("unknown attribute %v of <%v>", attr.Name.Local, element.Name.Local)
+				}
+			}
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		}
+	}
+}
+
+// xmlKeys collects the element paths ("keys>key") and attributes
+// ("keys>key@id") of the type
+func xmlKeys(structType reflect.Type, prefix string, known map[string]bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		options := strings.Split(field.Tag.Get("xml"), ",")
+		name := options[0]
+		if name == "-" || field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		kind := ""
+		if len(options) > 1 {
+			kind = options[1]
+		}
+		switch kind {
+		case "attr":
+			known[strings.TrimSuffix(prefix, ">")+"@"+name] = true
+			continue
+		case "chardata", "innerxml", "comment":
+			continue
+		}
+		key := prefix
+		for _, part := range strings.Split(name, ">") {
+			key += part
+			known[key] = true
+			key += ">"
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Slice {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct {
+			xmlKeys(fieldType, key, known)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_LoadXMLConfig_ShouldRejectUnknownElementsAndAttributes(t *testing.T) {
+	createConfigFile("config.xml", "<config><port>1234</port><rootDir>/srv</rootDir></config>")
+	defer deleteConfigFile("config.xml")
+	server := Server{}
+	err := server.LoadXMLConfig("config.xml")
+	assert.NotEqual(t, nil, err)
+	assert.True(t, strings.Contains(err.Error(), "rootDir"))
+
+	createConfigFile("config.xml", "<config><keys><key id=\"1\" retired=\"2017-01-01\">k</key></keys></config>")
+	err = server.LoadXMLConfig("config.xml")
+	assert.NotEqual(t, nil, err)
+	assert.True(t, strings.Contains(err.Error(), "retired"))
+
+	createConfigFile("config.xml", "<config><keys><key id=\"1\" retire=\"2017-01-01\" active=\"true\">k</key></keys>"+
+		"<tls enabled=\"true\"><cert>cert.pem</cert></tls></config>")
+	err = server.LoadXMLConfig("config.xml")
+	assert.Equal(t, nil, err)
+	assert.True(t, server.Config.TLS.Enabled)
+}
+
+func Test_LoadXMLConfig_ShouldReturnSyntaxErrors(t *testing.T) {
+	createConfigFile("config.xml", "<config><port>1234</config>")
+	defer deleteConfigFile("config.xml")
+	server := Server{}
+
+	err := server.LoadXMLConfig("config.xml")
+	_, missingErr := server.readConfig("missing.xml")
+
+	assert.NotEqual(t, nil, err)
+	assert.NotEqual(t, nil, missingErr)
+}
+
+func Test_LoadJSONConfig_ShouldRejectUnknownKeys(t *testing.T) {
+	createConfigFile("config.json", "{\"port\":1234,\"rootDir\":\"/srv\"}")
+	defer deleteConfigFile("config.json")
+	server := Server{}
+
+	err := server.LoadJSONConfig("config.json")
+
+	assert.NotEqual(t, nil, err)
+	assert.True(t, strings.Contains(err.Error(), "rootDir"))
+}
+
+func Test_LoadJSONConfig_ShouldLoadGoroutineLevels(t *testing.T) {
+	createConfigFile("config.json", "{\"goroutineLevels\":3}")
+	defer deleteConfigFile("config.json")
+	server := Server{}
+
+	err := server.LoadJSONConfig("config.json")
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 3, server.Config.GoroutineLevels)
+}
+
+func Test_LoadYAMLConfig_ShouldRejectUnknownKeys(t *testing.T) {
+	createConfigFile("config.yaml", "port: 1234\nroots: /srv")
+	defer deleteConfigFile("config.yaml")
+	server := Server{}
+
+	err := server.LoadYAMLConfig("config.yaml")
+
+	assert.NotEqual(t, nil, err)
+}
+
+func Test_Validate_ShouldListEveryProblem(t *testing.T) {
+	server := Server{Config: ServerConfig{
+		Port:        70000,
+		RootDir:     "missing_root",
+		Key:         "short",
+		ReadTimeout: "soon",
+		TLS:         TLSConfig{Enabled: true, MinVersion: "2.0", Cert: "cert.pem"},
+	}}
+
+	err := server.Validate()
+
+	configErr, ok := err.(ConfigError)
+	assert.True(t, ok)
+	assert.Equal(t, 6, len(configErr.Problems), err.Error())
+	assert.True(t, strings.Contains(err.Error(), "port"))
+	assert.True(t, strings.Contains(err.Error(), "missing_root"))
+	assert.True(t, strings.Contains(err.Error(), "key"))
+	assert.True(t, strings.Contains(err.Error(), "readTimeout"))
+	assert.True(t, strings.Contains(err.Error(), "minVersion"))
+	assert.True(t, strings.Contains(err.Error(), "cert and key"))
+}
+
+func Test_Validate_ShouldAcceptValidConfig(t *testing.T) {
+	os.Mkdir("rootDir", 0777)
+	defer os.RemoveAll("rootDir")
+	server := Server{Config: ServerConfig{Port: 8080, RootDir: "rootDir", Key: "k3J9xQ2mP7vR4tW8"}}
+	fileServer := Server{Config: ServerConfig{Port: 8080, RootDir: "server.go", Key: "k3J9xQ2mP7vR4tW8"}}
+
+	err := server.Validate()
+	fileErr := fileServer.Validate()
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ConfigError{[]string{"root server.go is not a directory"}}, fileErr)
+}
@@ -0,0 +1,64 @@
+// session package issues cookies which identify browser sessions, so that
+// path tokens can be bound to the session they were issued to
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+)
+
+// CookieName is the name of the session cookie
+const CookieName = "session"
+
+// idSize is the number of random bytes of session IDs
+const idSize = 16
+
+type contextKey struct{}
+
+// Middleware makes sure every request has a session. Browsers without a
+// valid session cookie get a new one, which lasts until the browser is
+// closed
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := ""
+		if cookie, err := r.Cookie(CookieName); err == nil && valid(cookie.Value) {
+			id = cookie.Value
+		} else {
+			random := make([]byte, idSize)
+			if _, err := rand.Read(random); err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			id = base64.RawURLEncoding.EncodeToString(random)
+			http.SetCookie(w, &http.Cookie{
+				Name:     CookieName,
+				Value:    id,
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+	})
+}
+
+// Expire removes the session cookie, so that the next request starts a
+// new session. Tokens bound to the old session stop working
+func Expire(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: CookieName, Path: "/", MaxAge: -1})
+}
+
+// ID returns the session ID of the request. It is empty for requests
+// which didn't pass Middleware
+func ID(r *http.Request) string {
+	id, _ := r.Context().Value(contextKey{}).(string)
+	return id
+}
+
+func valid(id string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(id)
+	return err == nil && len(decoded) == idSize
+}
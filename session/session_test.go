@@ -0,0 +1,57 @@
+package session
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func serve(r *http.Request) (w *httptest.ResponseRecorder, id string) {
+	w = httptest.NewRecorder()
+	Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id = ID(r)
+	})).ServeHTTP(w, r)
+	return
+}
+
+func Test_Middleware_ShouldIssueSessionCookie(t *testing.T) {
+	w, id := serve(httptest.NewRequest("GET", "/", nil))
+
+	cookies := w.Result().Cookies()
+	assert.Equal(t, 1, len(cookies))
+	assert.Equal(t, CookieName, cookies[0].Name)
+	assert.Equal(t, id, cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.NotEqual(t, "", id)
+}
+
+func Test_Middleware_ShouldKeepValidSession(t *testing.T) {
+	_, id := serve(httptest.NewRequest("GET", "/", nil))
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: CookieName, Value: id})
+	forged := httptest.NewRequest("GET", "/", nil)
+	forged.AddCookie(&http.Cookie{Name: CookieName, Value: "admin"})
+
+	w, sameID := serve(r)
+	_, forgedID := serve(forged)
+
+	assert.Equal(t, id, sameID)
+	assert.Equal(t, 0, len(w.Result().Cookies()))
+	assert.NotEqual(t, "admin", forgedID)
+}
+
+func Test_ID_ShouldBeEmptyWithoutMiddleware(t *testing.T) {
+	assert.Equal(t, "", ID(httptest.NewRequest("GET", "/", nil)))
+}
+
+func Test_Expire_ShouldRemoveSessionCookie(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	Expire(w)
+	cookies := w.Result().Cookies()
+
+	assert.Equal(t, 1, len(cookies))
+	assert.Equal(t, CookieName, cookies[0].Name)
+	assert.Equal(t, -1, cookies[0].MaxAge)
+}
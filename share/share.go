@@ -0,0 +1,247 @@
+// share package manages links which give access to a single file or
+// directory without logging in
+package share
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/doojin/file-explorer/crypto"
+	"golang.org/x/crypto/bcrypt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Scopes of shares
+const (
+	ScopeRead   = "read"
+	ScopeUpload = "upload"
+)
+
+var (
+	ERR_INVALID_SCOPE   = errors.New("Unknown share scope")
+	ERR_SHARE_NOT_FOUND = errors.New("Share doesn't exist or was revoked")
+	ERR_SHARE_EXPIRED   = errors.New("Share has expired")
+	ERR_DOWNLOAD_LIMIT  = errors.New("Share has reached its download limit")
+	ERR_TOKEN_MISMATCH  = errors.New("Share token doesn't match the share")
+)
+
+// Share gives access to the file or directory at Path until ExpiresAt.
+// MaxDownloads of zero means unlimited downloads. Owner is the user who
+// created the share, empty without authentication
+type Share struct {
+	ID           string    `json:"id"`
+	Owner        string    `json:"owner,omitempty"`
+	Path         string    `json:"path"`
+	Scope        string    `json:"scope"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+	PasswordHash string    `json:"passwordHash,omitempty"`
+	MaxDownloads int       `json:"maxDownloads"`
+	Downloads    int       `json:"downloads"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// HasPassword checks if the share is protected with a password
+func (share Share) HasPassword() bool {
+	return share.PasswordHash != ""
+}
+
+// tokenPurpose separates signatures of share tokens from other signed
+// tokens
+const tokenPurpose = "share"
+
+// claims is the payload of signed share tokens
+type claims struct {
+	ID      string `json:"id"`
+	Path    string `json:"path"`
+	Scope   string `json:"scope"`
+	Expires int64  `json:"exp"`
+}
+
+// Token returns the signed token of the share used in share links
+func Token(encoder crypto.Encoder, share Share) string {
+	payload, _ := json.Marshal(claims{share.ID, share.Path, share.Scope, share.ExpiresAt.Unix()})
+	return encoder.Sign(tokenPurpose, payload)
+}
+
+// Store keeps shares in a JSON file, so that they survive restarts and
+// can be listed and revoked
+type Store struct {
+	mutex  sync.Mutex
+	file   string
+	shares map[string]*Share
+	now    func() time.Time
+}
+
+// NewStore loads the shares from the file. A missing file means there
+// are no shares yet
+func NewStore(file string) (store *Store, err error) {
+	store = &Store{file: file, shares: map[string]*Share{}, now: time.Now}
+	content, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var shares []*Share
+	if err = json.Unmarshal(content, &shares); err != nil {
+		return nil, err
+	}
+	for _, share := range shares {
+		store.shares[share.ID] = share
+	}
+	return
+}
+
+// Create stores a new share of the owner. An empty password means no
+// password
+func (store *Store) Create(owner string, path string, scope string, expiresAt time.Time,
+	password string, maxDownloads int) (share Share, err error) {
+	if scope != ScopeRead && scope != ScopeUpload {
+		return share, ERR_INVALID_SCOPE
+	}
+	share = Share{
+		ID:           newID(),
+		Owner:        owner,
+		Path:         path,
+		Scope:        scope,
+		ExpiresAt:    expiresAt,
+		MaxDownloads: maxDownloads,
+		CreatedAt:    store.now(),
+	}
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return share, err
+		}
+		share.PasswordHash = string(hash)
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.shares[share.ID] = &share
+	return share, store.save()
+}
+
+// Resolve verifies the share token and returns the share it points to
+func (store *Store) Resolve(encoder crypto.Encoder, token string) (share Share, err error) {
+	payload, err := encoder.Verify(tokenPurpose, token)
+	if err != nil {
+		return
+	}
+	var tokenClaims claims
+	if err = json.Unmarshal(payload, &tokenClaims); err != nil {
+		return share, crypto.ERR_MALFORMED_TOKEN
+	}
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, ok := store.shares[tokenClaims.ID]
+	if !ok {
+		return share, ERR_SHARE_NOT_FOUND
+	}
+	if stored.Path != tokenClaims.Path || stored.Scope != tokenClaims.Scope ||
+		stored.ExpiresAt.Unix() != tokenClaims.Expires {
+		return share, ERR_TOKEN_MISMATCH
+	}
+	if !store.now().Before(stored.ExpiresAt) {
+		return share, ERR_SHARE_EXPIRED
+	}
+	return *stored, nil
+}
+
+// CheckPassword checks the password of the share
+func (store *Store) CheckPassword(share Share, password string) bool {
+	if !share.HasPassword() {
+		return true
+	}
+	return bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(password)) == nil
+}
+
+// CountDownload registers a download of the share unless its limit is
+// reached
+func (store *Store) CountDownload(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	share, ok := store.shares[id]
+	if !ok {
+		return ERR_SHARE_NOT_FOUND
+	}
+	if share.MaxDownloads > 0 && share.Downloads >= share.MaxDownloads {
+		return ERR_DOWNLOAD_LIMIT
+	}
+	share.Downloads++
+	return store.save()
+}
+
+// Active returns the shares which haven't expired, the newest first.
+// Expired shares are removed from the store
+func (store *Store) Active() (shares []Share) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	now := store.now()
+	removed := false
+	for id, share := range store.shares {
+		if !now.Before(share.ExpiresAt) {
+			delete(store.shares, id)
+			removed = true
+			continue
+		}
+		shares = append(shares, *share)
+	}
+	if removed {
+		store.save()
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].CreatedAt.After(shares[j].CreatedAt)
+	})
+	return
+}
+
+// Revoke deletes the share, its links stop working immediately
+func (store *Store) Revoke(id string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if _, ok := store.shares[id]; !ok {
+		return ERR_SHARE_NOT_FOUND
+	}
+	delete(store.shares, id)
+	return store.save()
+}
+
+// save writes all shares to the file. Must be called under the lock
+func (store *Store) save() error {
+	shares := []*Share{}
+	for _, share := range store.shares {
+		shares = append(shares, share)
+	}
+	content, err := json.MarshalIndent(shares, "", "  ")
+	if err != nil {
+		return err
+	}
+	temp, err := ioutil.TempFile(filepath.Dir(store.file), ".shares")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(content)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), store.file)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+	}
+	return err
+}
+
+func newID() string {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,126 @@
+package share
+
+import (
+	"github.com/doojin/file-explorer/crypto"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sharesFile = "shares_test.json"
+
+func newTestStore(t *testing.T) *Store {
+	os.Remove(sharesFile)
+	store, err := NewStore(sharesFile)
+	assert.Equal(t, nil, err)
+	return store
+}
+
+func Test_Resolve_ShouldReturnShareOfToken(t *testing.T) {
+	defer os.Remove(sharesFile)
+	store := newTestStore(t)
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	created, _ := store.Create("", "C:/share/report.pdf", ScopeRead, time.Now().Add(time.Hour), "", 3)
+
+	resolved, err := store.Resolve(encoder, Token(encoder, created))
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, created.ID, resolved.ID)
+	assert.Equal(t, "C:/share/report.pdf", resolved.Path)
+	assert.Equal(t, 3, resolved.MaxDownloads)
+}
+
+func Test_Resolve_ShouldRejectExpiredRevokedAndForgedShares(t *testing.T) {
+	defer os.Remove(sharesFile)
+	store := newTestStore(t)
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	otherEncoder, _ := crypto.NewEncoder("6543210987654321")
+	expiring, _ := store.Create("", "C:/share/a", ScopeRead, time.Now().Add(time.Hour), "", 0)
+	revoked, _ := store.Create("", "C:/share/b", ScopeRead, time.Now().Add(time.Hour), "", 0)
+	store.Revoke(revoked.ID)
+	store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+
+	_, expiredErr := store.Resolve(encoder, Token(encoder, expiring))
+	_, revokedErr := store.Resolve(encoder, Token(encoder, revoked))
+	_, forgedErr := store.Resolve(encoder, Token(otherEncoder, expiring))
+
+	assert.Equal(t, ERR_SHARE_EXPIRED, expiredErr)
+	assert.Equal(t, ERR_SHARE_NOT_FOUND, revokedErr)
+	assert.Equal(t, crypto.ERR_INVALID_SIGNATURE, forgedErr)
+}
+
+func Test_Resolve_ShouldRejectTokenWithChangedClaims(t *testing.T) {
+	defer os.Remove(sharesFile)
+	store := newTestStore(t)
+	encoder, _ := crypto.NewEncoder("1234567890123456")
+	created, _ := store.Create("", "C:/share/a", ScopeUpload, time.Now().Add(time.Hour), "", 0)
+	created.Scope = ScopeRead
+
+	_, err := store.Resolve(encoder, Token(encoder, created))
+
+	assert.Equal(t, ERR_TOKEN_MISMATCH, err)
+}
+
+func Test_Create_ShouldRejectUnknownScope(t *testing.T) {
+	defer os.Remove(sharesFile)
+	store := newTestStore(t)
+
+	_, err := store.Create("", "C:/share/a", "delete", time.Now().Add(time.Hour), "", 0)
+
+	assert.Equal(t, ERR_INVALID_SCOPE, err)
+}
+
+func Test_CheckPassword_ShouldCompareWithHash(t *testing.T) {
+	defer os.Remove(sharesFile)
+	store := newTestStore(t)
+	protected, _ := store.Create("", "C:/share/a", ScopeRead, time.Now().Add(time.Hour), "secret", 0)
+	open, _ := store.Create("", "C:/share/b", ScopeRead, time.Now().Add(time.Hour), "", 0)
+
+	assert.True(t, protected.HasPassword())
+	assert.False(t, strings.Contains(protected.PasswordHash, "secret"))
+	assert.True(t, store.CheckPassword(protected, "secret"))
+	assert.False(t, store.CheckPassword(protected, "Secret"))
+	assert.True(t, store.CheckPassword(open, ""))
+}
+
+func Test_CountDownload_ShouldStopAtLimit(t *testing.T) {
+	defer os.Remove(sharesFile)
+	store := newTestStore(t)
+	limited, _ := store.Create("", "C:/share/a", ScopeRead, time.Now().Add(time.Hour), "", 2)
+	unlimited, _ := store.Create("", "C:/share/b", ScopeRead, time.Now().Add(time.Hour), "", 0)
+
+	assert.Equal(t, nil, store.CountDownload(limited.ID))
+	assert.Equal(t, nil, store.CountDownload(limited.ID))
+	assert.Equal(t, ERR_DOWNLOAD_LIMIT, store.CountDownload(limited.ID))
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, nil, store.CountDownload(unlimited.ID))
+	}
+}
+
+func Test_NewStore_ShouldLoadSavedShares(t *testing.T) {
+	defer os.Remove(sharesFile)
+	store := newTestStore(t)
+	created, _ := store.Create("", "C:/share/a", ScopeRead, time.Now().Add(time.Hour), "", 2)
+	store.CountDownload(created.ID)
+
+	loaded, err := NewStore(sharesFile)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(loaded.Active()))
+	assert.Equal(t, 1, loaded.Active()[0].Downloads)
+}
+
+func Test_Active_ShouldDropExpiredShares(t *testing.T) {
+	defer os.Remove(sharesFile)
+	store := newTestStore(t)
+	store.Create("", "C:/share/a", ScopeRead, time.Now().Add(time.Minute), "", 0)
+	store.Create("", "C:/share/b", ScopeRead, time.Now().Add(time.Hour), "", 0)
+	store.now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+
+	active := store.Active()
+
+	assert.Equal(t, 1, len(active))
+	assert.Equal(t, "C:/share/b", active[0].Path)
+}
@@ -0,0 +1,135 @@
+package thumbnail
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const cacheExtension = ".jpg"
+
+// Cache stores thumbnails on disk. When the total size exceeds the limit,
+// the least recently used thumbnails are removed
+type Cache struct {
+	dir     string
+	maxSize int64
+	mutex   sync.Mutex
+	size    int64
+	hits    int64
+	misses  int64
+}
+
+// NewCache returns a cache stored in dir, creating it if needed
+func NewCache(dir string, maxSize int64) (cache *Cache, err error) {
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	cache = &Cache{dir: dir, maxSize: maxSize}
+	entries, err := cache.entries()
+	for _, entry := range entries {
+		cache.size += entry.Size()
+	}
+	return
+}
+
+// Key builds the cache key of a thumbnail
+func Key(filePath string, fileSize int64, modTime time.Time, size int) string {
+	hash := sha256.Sum256([]byte(filePath + "\x00" +
+		strconv.FormatInt(fileSize, 10) + "\x00" +
+		strconv.FormatInt(modTime.UnixNano(), 10) + "\x00" +
+		strconv.Itoa(size)))
+	return hex.EncodeToString(hash[:])
+}
+
+// Get returns the cached thumbnail and marks it as recently used
+func (cache *Cache) Get(key string) (content []byte, ok bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	content, err := ioutil.ReadFile(cache.path(key))
+	if err != nil {
+		cache.misses++
+		return nil, false
+	}
+	cache.hits++
+	now := time.Now()
+	os.Chtimes(cache.path(key), now, now)
+	return content, true
+}
+
+// Put stores the thumbnail and evicts old ones if the cache is full
+func (cache *Cache) Put(key string, content []byte) error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	temp, err := ioutil.TempFile(cache.dir, "tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(content)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), cache.path(key))
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	cache.size += int64(len(content))
+	return cache.evict()
+}
+
+// Stats returns the amount of cache hits and misses
+func (cache *Cache) Stats() (hits int64, misses int64) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.hits, cache.misses
+}
+
+// evict removes the least recently used thumbnails until the cache fits
+// into its size. Must be called under the lock
+func (cache *Cache) evict() error {
+	if cache.size <= cache.maxSize {
+		return nil
+	}
+	entries, err := cache.entries()
+	if err != nil {
+		return err
+	}
+	cache.size = 0
+	for _, entry := range entries {
+		cache.size += entry.Size()
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ModTime().Before(entries[j].ModTime())
+	})
+	for _, entry := range entries {
+		if cache.size <= cache.maxSize {
+			break
+		}
+		if err := os.Remove(filepath.Join(cache.dir, entry.Name())); err == nil {
+			cache.size -= entry.Size()
+		}
+	}
+	return nil
+}
+
+func (cache *Cache) entries() (entries []os.FileInfo, err error) {
+	infos, err := ioutil.ReadDir(cache.dir)
+	for _, info := range infos {
+		if !info.IsDir() && filepath.Ext(info.Name()) == cacheExtension {
+			entries = append(entries, info)
+		}
+	}
+	return
+}
+
+func (cache *Cache) path(key string) string {
+	return filepath.Join(cache.dir, key+cacheExtension)
+}
@@ -0,0 +1,51 @@
+package thumbnail
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_Key_ShouldChangeWhenFileChanges(t *testing.T) {
+	modTime := time.Now()
+
+	key := Key("dir/image.png", 100, modTime, 160)
+
+	assert.Equal(t, key, Key("dir/image.png", 100, modTime, 160))
+	assert.NotEqual(t, key, Key("dir/image.png", 101, modTime, 160))
+	assert.NotEqual(t, key, Key("dir/image.png", 100, modTime.Add(time.Second), 160))
+	assert.NotEqual(t, key, Key("dir/image.png", 100, modTime, 80))
+	assert.NotEqual(t, key, Key("dir/other.png", 100, modTime, 160))
+}
+
+func Test_Put_ShouldEvictLeastRecentlyUsedThumbnails(t *testing.T) {
+	defer os.RemoveAll("cacheDir")
+	cache, _ := NewCache("cacheDir", 20)
+	old := time.Now().Add(-time.Hour)
+
+	cache.Put("first", []byte("0123456789"))
+	os.Chtimes(cache.path("first"), old, old)
+	cache.Put("second", []byte("0123456789"))
+	os.Chtimes(cache.path("second"), old.Add(time.Minute), old.Add(time.Minute))
+	cache.Get("first")
+	cache.Put("third", []byte("0123456789"))
+
+	_, firstOk := cache.Get("first")
+	_, secondOk := cache.Get("second")
+	_, thirdOk := cache.Get("third")
+	assert.True(t, firstOk)
+	assert.False(t, secondOk)
+	assert.True(t, thirdOk)
+}
+
+func Test_NewCache_ShouldCountExistingThumbnails(t *testing.T) {
+	defer os.RemoveAll("cacheDir")
+	cache, _ := NewCache("cacheDir", 100)
+	cache.Put("first", []byte("0123456789"))
+
+	reopened, err := NewCache("cacheDir", 100)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, int64(10), reopened.size)
+}
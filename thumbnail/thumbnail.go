@@ -0,0 +1,219 @@
+// thumbnail package generates small previews of images and caches them
+// on disk
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"github.com/doojin/file-explorer/explorer"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"time"
+)
+
+var (
+	ERR_UNSUPPORTED   = errors.New("Thumbnails are not supported for this file")
+	ERR_IMAGE_TOO_BIG = errors.New("Image is too big for a thumbnail")
+)
+
+// MaxPixels limits the size of decoded source images
+const MaxPixels = 50 * 1000 * 1000
+
+// Supported checks if a thumbnail can be generated for the file name.
+// Thumbnails are generated for all images the explorer shows
+func Supported(name string) bool {
+	return explorer.IsImage(name)
+}
+
+// Opener opens the source image
+type Opener func() (io.ReadCloser, error)
+
+// Thumbnailer generates thumbnails with limited concurrency and stores
+// them in the cache
+type Thumbnailer struct {
+	cache *Cache
+	slots chan bool
+}
+
+// New returns a new instance of Thumbnailer
+func New(cache *Cache, concurrency int) *Thumbnailer {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Thumbnailer{
+		cache: cache,
+		slots: make(chan bool, concurrency),
+	}
+}
+
+// Thumbnail returns a JPEG thumbnail which fits into size x size pixels.
+// Thumbnails are cached using the file path, size and modification time,
+// so a changed file gets a new thumbnail
+func (thumbnailer *Thumbnailer) Thumbnail(filePath string, fileSize int64, modTime time.Time,
+	size int, open Opener) (content []byte, err error) {
+	if !Supported(filePath) {
+		return nil, ERR_UNSUPPORTED
+	}
+	key := Key(filePath, fileSize, modTime, size)
+	if content, ok := thumbnailer.cache.Get(key); ok {
+		return content, nil
+	}
+	thumbnailer.slots <- true
+	defer func() { <-thumbnailer.slots }()
+	// Another request could have generated it while waiting for a slot
+	if content, ok := thumbnailer.cache.Get(key); ok {
+		return content, nil
+	}
+	content, err = Generate(open, size)
+	if err != nil {
+		return
+	}
+	thumbnailer.cache.Put(key, content)
+	return
+}
+
+// Stats returns the amount of cache hits and misses
+func (thumbnailer *Thumbnailer) Stats() (hits int64, misses int64) {
+	return thumbnailer.cache.Stats()
+}
+
+// Generate decodes the image and encodes its scaled down copy as JPEG
+func Generate(open Opener, size int) (content []byte, err error) {
+	reader, err := open()
+	if err != nil {
+		return
+	}
+	config, _, err := image.DecodeConfig(reader)
+	reader.Close()
+	if err != nil {
+		return nil, ERR_UNSUPPORTED
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ERR_IMAGE_TOO_BIG
+	}
+	reader, err = open()
+	if err != nil {
+		return
+	}
+	source, _, err := image.Decode(reader)
+	reader.Close()
+	if err != nil {
+		return nil, ERR_UNSUPPORTED
+	}
+	buffer := new(bytes.Buffer)
+	err = jpeg.Encode(buffer, Scale(source, size), &jpeg.Options{Quality: 80})
+	return buffer.Bytes(), err
+}
+
+// Scale returns a copy of the image which fits into size x size pixels
+// keeping its proportions. Every target pixel is the average of the source
+// pixels it covers, transparent areas are drawn on white background. The
+// source is sampled in place, large images aren't copied
+func Scale(source image.Image, size int) image.Image {
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	targetWidth, targetHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			targetWidth, targetHeight = size, height*size/width
+		} else {
+			targetWidth, targetHeight = width*size/height, size
+		}
+	}
+	if targetWidth < 1 {
+		targetWidth = 1
+	}
+	if targetHeight < 1 {
+		targetHeight = 1
+	}
+	pixel := onWhite(source)
+	target := image.NewRGBA(image.Rect(0, 0, targetWidth, targetHeight))
+	for y := 0; y < targetHeight; y++ {
+		fromY, toY := y*height/targetHeight, (y+1)*height/targetHeight
+		if toY == fromY {
+			toY++
+		}
+		for x := 0; x < targetWidth; x++ {
+			fromX, toX := x*width/targetWidth, (x+1)*width/targetWidth
+			if toX == fromX {
+				toX++
+			}
+			var r, g, b, count uint32
+			for sourceY := bounds.Min.Y + fromY; sourceY < bounds.Min.Y+toY; sourceY++ {
+				for sourceX := bounds.Min.X + fromX; sourceX < bounds.Min.X+toX; sourceX++ {
+					pixelR, pixelG, pixelB := pixel(sourceX, sourceY)
+					r += pixelR
+					g += pixelG
+					b += pixelB
+					count++
+				}
+			}
+			target.SetRGBA(x, y, color.RGBA{
+				uint8(r / count),
+				uint8(g / count),
+				uint8(b / count),
+				255,
+			})
+		}
+	}
+	return target
+}
+
+// onWhite returns a function which reads the 8 bit color components of a
+// source pixel drawn on white background. Image types of the supported
+// formats are read directly, other types through At
+func onWhite(source image.Image) func(x int, y int) (r uint32, g uint32, b uint32) {
+	switch source := source.(type) {
+	case *image.YCbCr:
+		// JPEG images have no transparency
+		return func(x int, y int) (uint32, uint32, uint32) {
+			yOffset, cOffset := source.YOffset(x, y), source.COffset(x, y)
+			r, g, b := color.YCbCrToRGB(source.Y[yOffset], source.Cb[cOffset], source.Cr[cOffset])
+			return uint32(r), uint32(g), uint32(b)
+		}
+	case *image.RGBA:
+		return func(x int, y int) (uint32, uint32, uint32) {
+			pixel := source.Pix[source.PixOffset(x, y):]
+			white := 255 - uint32(pixel[3])
+			return uint32(pixel[0]) + white, uint32(pixel[1]) + white, uint32(pixel[2]) + white
+		}
+	case *image.NRGBA:
+		return func(x int, y int) (uint32, uint32, uint32) {
+			pixel := source.Pix[source.PixOffset(x, y):]
+			// The 16 bit arithmetic of color.NRGBA
+			alpha := uint32(pixel[3]) * 0x101
+			white := 0xffff - alpha
+			return (uint32(pixel[0])*0x101*alpha/0xffff + white) >> 8,
+				(uint32(pixel[1])*0x101*alpha/0xffff + white) >> 8,
+				(uint32(pixel[2])*0x101*alpha/0xffff + white) >> 8
+		}
+	case *image.Paletted:
+		// GIF images have few colors, they are drawn on white once
+		colors := make([][3]uint32, len(source.Palette))
+		for index, paletteColor := range source.Palette {
+			colors[index] = whiteComponents(paletteColor)
+		}
+		return func(x int, y int) (uint32, uint32, uint32) {
+			index := int(source.Pix[source.PixOffset(x, y)])
+			if index >= len(colors) {
+				return 0, 0, 0
+			}
+			return colors[index][0], colors[index][1], colors[index][2]
+		}
+	}
+	return func(x int, y int) (uint32, uint32, uint32) {
+		components := whiteComponents(source.At(x, y))
+		return components[0], components[1], components[2]
+	}
+}
+
+// whiteComponents returns the 8 bit components of the color drawn on white
+func whiteComponents(pixel color.Color) [3]uint32 {
+	r, g, b, a := pixel.RGBA()
+	white := 0xffff - a
+	return [3]uint32{(r + white) >> 8, (g + white) >> 8, (b + white) >> 8}
+}
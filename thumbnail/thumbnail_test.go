@@ -0,0 +1,121 @@
+package thumbnail
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func pngOpener(width int, height int) Opener {
+	source := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			source.Set(x, y, color.RGBA{255, 0, 0, 255})
+		}
+	}
+	buffer := new(bytes.Buffer)
+	png.Encode(buffer, source)
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buffer.Bytes())), nil
+	}
+}
+
+func Test_Supported_ShouldAcceptOnlyJPEGPNGAndGIF(t *testing.T) {
+	assert.True(t, Supported("photo.JPG"))
+	assert.True(t, Supported("screenshot.png"))
+	assert.True(t, Supported("animation.gif"))
+	assert.False(t, Supported("document.pdf"))
+}
+
+func Test_Scale_ShouldKeepProportions(t *testing.T) {
+	wide := Scale(image.NewRGBA(image.Rect(0, 0, 400, 200)), 100)
+	tall := Scale(image.NewRGBA(image.Rect(0, 0, 200, 400)), 100)
+	small := Scale(image.NewRGBA(image.Rect(0, 0, 50, 20)), 100)
+
+	assert.Equal(t, image.Rect(0, 0, 100, 50), wide.Bounds())
+	assert.Equal(t, image.Rect(0, 0, 50, 100), tall.Bounds())
+	assert.Equal(t, image.Rect(0, 0, 50, 20), small.Bounds())
+}
+
+func Test_Scale_ShouldDrawTransparentAreasOnWhite(t *testing.T) {
+	scaled := Scale(image.NewRGBA(image.Rect(0, 0, 10, 10)), 5)
+
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, scaled.At(0, 0))
+}
+
+// genericImage hides the type of the image, so that it is read with At
+type genericImage struct {
+	image.Image
+}
+
+func Test_Scale_ShouldReadImageTypesLikeAt(t *testing.T) {
+	bounds := image.Rect(3, 5, 43, 25)
+	nrgba := image.NewNRGBA(bounds)
+	rgba := image.NewRGBA(bounds)
+	paletted := image.NewPaletted(bounds, color.Palette{color.Transparent, color.RGBA{0, 0, 255, 255}})
+	ycbcr := image.NewYCbCr(bounds, image.YCbCrSubsampleRatio420)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := color.NRGBA{uint8(x * 6), uint8(y * 9), 200, uint8(x * y)}
+			nrgba.Set(x, y, pixel)
+			rgba.Set(x, y, pixel)
+			paletted.SetColorIndex(x, y, uint8(x%2))
+			ycbcr.Y[ycbcr.YOffset(x, y)] = uint8(x * y)
+			ycbcr.Cb[ycbcr.COffset(x, y)] = uint8(x * 6)
+			ycbcr.Cr[ycbcr.COffset(x, y)] = uint8(y * 9)
+		}
+	}
+
+	for _, source := range []image.Image{nrgba, rgba, paletted, ycbcr} {
+		assert.Equal(t, Scale(genericImage{source}, 10), Scale(source, 10))
+	}
+}
+
+func Test_Generate_ShouldEncodeJPEGThumbnail(t *testing.T) {
+	content, err := Generate(pngOpener(300, 150), 100)
+
+	assert.Equal(t, nil, err)
+	thumbnail, err := jpeg.Decode(bytes.NewReader(content))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumbnail.Bounds())
+}
+
+func Test_Generate_ShouldReturnErrorForBrokenImage(t *testing.T) {
+	_, err := Generate(func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader([]byte("not an image"))), nil
+	}, 100)
+
+	assert.Equal(t, ERR_UNSUPPORTED, err)
+}
+
+func Test_Thumbnail_ShouldCacheGeneratedThumbnails(t *testing.T) {
+	defer os.RemoveAll("cacheDir")
+	cache, _ := NewCache("cacheDir", 1024*1024)
+	thumbnailer := New(cache, 2)
+	modTime := time.Now()
+	opened := 0
+	opener := pngOpener(20, 20)
+	counting := func() (io.ReadCloser, error) {
+		opened++
+		return opener()
+	}
+
+	first, firstErr := thumbnailer.Thumbnail("dir/image.png", 100, modTime, 10, counting)
+	second, secondErr := thumbnailer.Thumbnail("dir/image.png", 100, modTime, 10, counting)
+
+	assert.Equal(t, nil, firstErr)
+	assert.Equal(t, nil, secondErr)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 2, opened)
+	hits, misses := cache.Stats()
+	assert.Equal(t, int64(1), hits)
+	assert.Equal(t, int64(2), misses)
+}